@@ -10,11 +10,34 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var addPatch bool
+
 var addCmd = &cobra.Command{
 	Use:   "add [file]",
 	Short: "Add file(s) to staging area",
-	Args:  cobra.MinimumNArgs(1),
+	Long: `Add file(s) to staging area.
+
+With -p, each changed hunk of the tracked files under the given paths (or the
+whole repository) is shown and you are asked what to do with it:
+  y  stage the hunk
+  n  leave it unstaged
+  s  split it into smaller hunks
+  e  edit it in the editor before staging
+  q  stop, keeping the hunks chosen so far`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if addPatch {
+			return nil
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
+		if addPatch {
+			if err := repo.AddPatch(args); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
 		for _, file := range args {
 			pwd, err := os.Getwd()
 			if err != nil {
@@ -51,5 +74,6 @@ var addCmd = &cobra.Command{
 }
 
 func init() {
+	addCmd.Flags().BoolVarP(&addPatch, "patch", "p", false, "Choose hunks to stage interactively")
 	rootCmd.AddCommand(addCmd)
 }
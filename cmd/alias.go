@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/airbornharsh/hit/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// aliasAnnotation marks the commands added to list aliases in help.
+const aliasAnnotation = "alias"
+
+// configuredAliases returns the alias.<name> entries by name.
+func configuredAliases() map[string]string {
+	aliases := make(map[string]string)
+	for _, entry := range config.All() {
+		if name, ok := strings.CutPrefix(entry.Key, "alias."); ok {
+			aliases[name] = entry.Value
+		}
+	}
+	return aliases
+}
+
+// isBuiltinCommand reports whether name is one of hit's own commands, which
+// always win over an alias of the same name.
+func isBuiltinCommand(name string) bool {
+	if name == "help" || name == "completion" {
+		return true
+	}
+	for _, c := range rootCmd.Commands() {
+		if _, ok := c.Annotations[aliasAnnotation]; ok {
+			continue
+		}
+		if c.Name() == name || c.HasAlias(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandAlias rewrites args while its first word is an alias. Aliases may
+// expand to other aliases. When the expansion is a shell command, it is
+// returned with the remaining arguments instead.
+func expandAlias(args []string, aliases map[string]string) (expanded []string, shell string, err error) {
+	var seen []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") && !isBuiltinCommand(args[0]) {
+		value, ok := aliases[args[0]]
+		if !ok {
+			break
+		}
+		if slices.Contains(seen, args[0]) {
+			return nil, "", fmt.Errorf("alias loop detected: %s -> %s", strings.Join(seen, " -> "), args[0])
+		}
+		seen = append(seen, args[0])
+
+		if command, ok := strings.CutPrefix(value, "!"); ok {
+			return args[1:], command, nil
+		}
+		words, err := splitWords(value)
+		if err != nil {
+			return nil, "", fmt.Errorf("bad alias.%s: %v", args[0], err)
+		}
+		if len(words) == 0 {
+			return nil, "", fmt.Errorf("alias.%s is empty", args[0])
+		}
+		args = append(words, args[1:]...)
+	}
+	return args, "", nil
+}
+
+// splitWords splits s into words the way a shell would, honouring single
+// and double quotes and backslash escapes.
+func splitWords(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape in '%s'", s)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// runShellAlias runs command in the shell with args as its positional
+// parameters and exits with its status.
+func runShellAlias(command string, args []string) {
+	var c *exec.Cmd
+	if runtime.GOOS == "windows" {
+		c = exec.Command("cmd", append([]string{"/C", command}, args...)...)
+	} else {
+		c = exec.Command("sh", append([]string{"-c", command + ` "$@"`, command}, args...)...)
+	}
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	err := c.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.ExitCode())
+	}
+	if err != nil {
+		fmt.Printf("Error: failed to run alias: %v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// addAliasCommands lists the aliases among the commands in help. Execute
+// expands aliases before dispatch, so these commands mostly serve help.
+func addAliasCommands(aliases map[string]string) {
+	for _, name := range slices.Sorted(maps.Keys(aliases)) {
+		if isBuiltinCommand(name) || strings.ContainsAny(name, " \t") {
+			continue
+		}
+		value := aliases[name]
+		rootCmd.AddCommand(&cobra.Command{
+			Use:                name,
+			Short:              fmt.Sprintf("Alias for '%s'", value),
+			Long:               fmt.Sprintf("'%s' is an alias for '%s', set with 'hit config set alias.%s'.", name, value, name),
+			Annotations:        map[string]string{aliasAnnotation: value},
+			DisableFlagParsing: true,
+			Run: func(cmd *cobra.Command, args []string) {
+				dispatch(append([]string{name}, args...), aliases)
+			},
+		})
+	}
+}
+
+// dispatch expands an alias at the start of args and runs the resulting
+// command.
+func dispatch(args []string, aliases map[string]string) {
+	expanded, shell, err := expandAlias(args, aliases)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if shell != "" {
+		runShellAlias(shell, expanded)
+	}
+	rootCmd.SetArgs(expanded)
+	if err := rootCmd.Execute(); err != nil {
+		println(err)
+		os.Exit(1)
+	}
+}
@@ -9,18 +9,33 @@ import (
 )
 
 var newBranch bool
+var checkoutOurs bool
+var checkoutTheirs bool
+var checkoutMerge bool
 var checkoutCmd = &cobra.Command{
 	Use:   "checkout [branch]",
-	Short: "Checkout a branch",
+	Short: "Checkout a branch or pick a side of a conflicted file",
 	Long: `Checkout a branch to switch to it or create a new branch.
 	
 Examples:
   hit checkout main          # Switch to the main branch
   hit checkout -b feature    # Create and switch to a new branch called 'feature'
   hit checkout -b feature <commit>  # Create 'feature' at the given commit and switch to it
-  hit checkout --branch dev  # Create and switch to a new branch called 'dev'`,
-	Args: cobra.RangeArgs(1, 2),
+  hit checkout --branch dev  # Create and switch to a new branch called 'dev'
+  hit checkout --ours f.txt  # Resolve a conflicted file with the current branch's version
+  hit checkout --theirs f.txt  # Resolve a conflicted file with the incoming version
+  hit checkout --merge f.txt   # Recreate the conflict markers in f.txt`,
+	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		if checkoutOurs || checkoutTheirs || checkoutMerge {
+			checkoutConflictedFiles(args)
+			return
+		}
+		if len(args) > 2 {
+			fmt.Println("Error: too many arguments")
+			os.Exit(1)
+		}
+
 		branch := args[0]
 
 		if newBranch {
@@ -51,7 +66,39 @@ Examples:
 	},
 }
 
+func checkoutConflictedFiles(paths []string) {
+	selected := 0
+	for _, flag := range []bool{checkoutOurs, checkoutTheirs, checkoutMerge} {
+		if flag {
+			selected++
+		}
+	}
+	if selected > 1 || newBranch {
+		fmt.Println("Error: --ours, --theirs, --merge and --branch cannot be combined")
+		os.Exit(1)
+	}
+
+	for _, path := range paths {
+		var err error
+		switch {
+		case checkoutOurs:
+			err = repo.CheckoutConflictSide(path, repo.FavorOurs)
+		case checkoutTheirs:
+			err = repo.CheckoutConflictSide(path, repo.FavorTheirs)
+		default:
+			err = repo.RecreateConflict(path)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
 func init() {
 	checkoutCmd.Flags().BoolVarP(&newBranch, "branch", "b", false, "Create a new branch and checkout to it")
+	checkoutCmd.Flags().BoolVar(&checkoutOurs, "ours", false, "Resolve the given conflicted files with our version")
+	checkoutCmd.Flags().BoolVar(&checkoutTheirs, "theirs", false, "Resolve the given conflicted files with their version")
+	checkoutCmd.Flags().BoolVarP(&checkoutMerge, "merge", "m", false, "Recreate the conflict markers in the given files")
 	rootCmd.AddCommand(checkoutCmd)
 }
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/airbornharsh/hit/internal/repo"
+	"github.com/spf13/cobra"
+)
+
+var cherryPickContinue bool
+var cherryPickSkip bool
+var cherryPickAbort bool
+var cherryPickMainline int
+var cherryPickRecordOrigin bool
+
+var cherryPickCmd = &cobra.Command{
+	Use:   "cherry-pick <commit>...",
+	Short: "Apply the changes introduced by existing commits",
+	Long: `Apply the change each commit made to its parent onto the current branch with a
+three-way merge, recording a new commit with the original author and message.
+
+When a commit does not apply cleanly the cherry-pick stops. Resolve the conflicts,
+stage the files with 'hit add' and run 'hit cherry-pick --continue'.
+
+Examples:
+  hit cherry-pick feature             # Pick the commit at the tip of feature
+  hit cherry-pick a1b2c3d e4f5a6b     # Pick several commits in order
+  hit cherry-pick origin/main         # Pick from a remote-tracking branch
+  hit cherry-pick -m 1 a1b2c3d        # Pick a merge commit relative to its first parent
+  hit cherry-pick -x a1b2c3d          # Note the original commit in the message
+  hit cherry-pick --continue          # Carry on after resolving conflicts
+  hit cherry-pick --skip              # Drop the commit that failed to apply
+  hit cherry-pick --abort             # Return to the state before the cherry-pick`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+		switch {
+		case cherryPickContinue:
+			err = repo.ContinueCherryPick()
+		case cherryPickSkip:
+			err = repo.SkipCherryPick()
+		case cherryPickAbort:
+			err = repo.AbortCherryPick()
+			if err == nil {
+				fmt.Println("Cherry-pick aborted")
+			}
+		case len(args) > 0:
+			err = repo.CherryPick(args, repo.CherryPickOptions{
+				Mainline:     cherryPickMainline,
+				RecordOrigin: cherryPickRecordOrigin,
+			})
+		default:
+			err = fmt.Errorf("no commits given")
+		}
+		if errors.Is(err, repo.ErrStoppedOnConflicts) {
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	cherryPickCmd.Flags().IntVarP(&cherryPickMainline, "mainline", "m", 0, "Parent number (1 or 2) to diff merge commits against")
+	cherryPickCmd.Flags().BoolVarP(&cherryPickRecordOrigin, "record-origin", "x", false, "Append the original commit hash to the message")
+	cherryPickCmd.Flags().BoolVar(&cherryPickContinue, "continue", false, "Continue after resolving conflicts")
+	cherryPickCmd.Flags().BoolVar(&cherryPickSkip, "skip", false, "Skip the commit that failed to apply")
+	cherryPickCmd.Flags().BoolVar(&cherryPickAbort, "abort", false, "Abort and restore the original branch")
+	cherryPickCmd.MarkFlagsMutuallyExclusive("continue", "skip", "abort")
+	rootCmd.AddCommand(cherryPickCmd)
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/airbornharsh/hit/internal/commit"
 
@@ -9,14 +10,35 @@ import (
 )
 
 var message string
+var commitSign bool
+var commitAmend bool
+var commitAuthor string
+var commitDate string
+var commitNoVerify bool
+var commitNoEdit bool
 
 var commitCmd = &cobra.Command{
 	Use:   "commit",
 	Short: "Record changes to the repository",
+	Long: `Record changes to the repository.
+
+Without -m the editor ($HIT_EDITOR, core.editor, $VISUAL or $EDITOR) opens on
+.hit/COMMIT_EDITMSG, with the staged changes listed as comments. Lines starting
+with '#' are dropped and an empty message aborts the commit. The message starts
+from the merge message while concluding a merge, from the old message with
+--amend, and otherwise from the file named by commit.template.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		hash, err := commit.CreateCommit(message)
+		hash, err := commit.CreateCommit(message, commit.Options{
+			Sign:     commitSign,
+			Amend:    commitAmend,
+			Author:   commitAuthor,
+			Date:     commitDate,
+			NoVerify: commitNoVerify,
+			NoEdit:   commitNoEdit,
+		})
 		if err != nil {
-			panic(err)
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
 		}
 
 		fmt.Printf("[hit] Commit created: %s\n", hash)
@@ -25,5 +47,11 @@ var commitCmd = &cobra.Command{
 
 func init() {
 	commitCmd.Flags().StringVarP(&message, "message", "m", "", "Commit message")
+	commitCmd.Flags().BoolVarP(&commitSign, "sign", "S", false, "Sign the commit with the signing key")
+	commitCmd.Flags().BoolVar(&commitAmend, "amend", false, "Replace the tip of the branch with the index, editing its message unless -m or --no-edit is given")
+	commitCmd.Flags().StringVar(&commitAuthor, "author", "", "Override the commit author, as \"Name <email>\"")
+	commitCmd.Flags().StringVar(&commitDate, "date", "", "Override the author date")
+	commitCmd.Flags().BoolVarP(&commitNoVerify, "no-verify", "n", false, "Skip the pre-commit and commit-msg hooks")
+	commitCmd.Flags().BoolVar(&commitNoEdit, "no-edit", false, "Use the merge or amended message without opening the editor")
 	rootCmd.AddCommand(commitCmd)
 }
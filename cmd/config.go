@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/airbornharsh/hit/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var configGlobal bool
+var configSystem bool
+var configLocal bool
+var configShowScope bool
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Get and set configuration options",
+	Long: `Get and set configuration options.
+
+Settings are read from three JSON files, each overriding the one before:
+  system   /etc/hitconfig (or $HIT_CONFIG_SYSTEM)
+  global   ~/.hitconfig (or $HIT_CONFIG_GLOBAL)
+  local    .hit/config in the repository
+
+'get' and 'list' show the effective values unless a scope is chosen; 'set' and
+'unset' change the local file unless --global or --system is given.
+
+Examples:
+  hit config set --global user.name "Ada Lovelace"
+  hit config set --global user.email ada@example.com
+  hit config set merge.conflictStyle diff3
+  hit config set --global alias.co checkout
+  hit config set --global alias.lg "log --show-signature"
+  hit config set alias.who '!hit config get user.name'
+  hit config get user.email
+  hit config unset --global color.ui
+  hit config list --show-scope`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the value of a key",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var value string
+		var ok bool
+		if scope, scoped := configScope(); scoped {
+			value, ok = loadConfigScope(scope).Get(args[0])
+		} else {
+			value, ok = config.Get(args[0])
+		}
+		if !ok {
+			os.Exit(1)
+		}
+		fmt.Println(value)
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set a key",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		scope, _ := configScope()
+		f := loadConfigScope(scope)
+		if err := f.Set(args[0], args[1]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := f.Save(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Remove a key",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		scope, _ := configScope()
+		f := loadConfigScope(scope)
+		if err := f.Unset(args[0]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := f.Save(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the configured keys and values",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		var entries []config.Entry
+		if scope, scoped := configScope(); scoped {
+			entries = loadConfigScope(scope).Entries()
+		} else {
+			entries = config.All()
+		}
+		for _, entry := range entries {
+			if configShowScope {
+				fmt.Printf("%s\t", entry.Scope)
+			}
+			fmt.Printf("%s=%s\n", entry.Key, entry.Value)
+		}
+	},
+}
+
+var configKeysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "List the keys hit understands",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		for _, key := range config.Keys {
+			kind := key.Type.String()
+			if len(key.Values) > 0 {
+				kind = strings.Join(key.Values, "|")
+			}
+			fmt.Printf("%-24s %-12s %s\n", key.Name, kind, key.Usage)
+		}
+	},
+}
+
+// configScope returns the scope chosen with --system, --global or --local,
+// defaulting to local. scoped is false when none was given.
+func configScope() (scope config.Scope, scoped bool) {
+	switch {
+	case configSystem:
+		return config.System, true
+	case configGlobal:
+		return config.Global, true
+	case configLocal:
+		return config.Local, true
+	}
+	return config.Local, false
+}
+
+func loadConfigScope(scope config.Scope) *config.File {
+	f, err := config.Load(scope)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	return f
+}
+
+func init() {
+	configCmd.PersistentFlags().BoolVar(&configGlobal, "global", false, "Use the global config file, ~/.hitconfig")
+	configCmd.PersistentFlags().BoolVar(&configSystem, "system", false, "Use the system config file, /etc/hitconfig")
+	configCmd.PersistentFlags().BoolVar(&configLocal, "local", false, "Use the repository config file, .hit/config")
+	configCmd.MarkFlagsMutuallyExclusive("global", "system", "local")
+	configListCmd.Flags().BoolVar(&configShowScope, "show-scope", false, "Show which file each value comes from")
+	configCmd.AddCommand(configGetCmd, configSetCmd, configUnsetCmd, configListCmd, configKeysCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -5,14 +5,17 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var logShowSignature bool
+
 var logCmd = &cobra.Command{
 	Use:   "log",
 	Short: "Show Commits",
 	Run: func(cmd *cobra.Command, args []string) {
-		commit.LogCommits()
+		commit.LogCommits(logShowSignature)
 	},
 }
 
 func init() {
+	logCmd.Flags().BoolVar(&logShowSignature, "show-signature", false, "Check and show the signature of signed commits")
 	rootCmd.AddCommand(logCmd)
 }
@@ -1,58 +1,202 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
+	"github.com/airbornharsh/hit/internal/commit"
+	"github.com/airbornharsh/hit/internal/config"
 	"github.com/airbornharsh/hit/internal/repo"
 	"github.com/airbornharsh/hit/internal/storage"
 	"github.com/spf13/cobra"
 )
 
 var hash string = ""
+var conflictStyle string
+var mergeStrategy string
+var mergeFavor string
+var mergeNoFF bool
+var mergeSquash bool
+var mergeAbort bool
+var mergeContinue bool
+var mergeDryRun bool
+var mergeNoVerify bool
+var mergeNoEdit bool
 var mergeCmd = &cobra.Command{
-	Use:   "merge [remote] [branch]",
+	Use:   "merge [revision | remote branch]",
 	Short: "Merge changes from another branch",
 	Long: `Merge changes from another branch into the current branch.
+
+The target can be any revision: a local branch, a remote-tracking branch
+(origin/main), HEAD or a commit hash. Without arguments the current branch's
+counterpart on origin is merged.
 	
 Examples:
-  hit merge origin main       # Merge remote main branch
-  hit merge -c <commit hash> # Merge the given commit hash into the current branch`,
+  hit merge feature          # Merge the local feature branch
+  hit merge origin/main      # Merge a remote-tracking branch
+  hit merge origin main      # Same as above
+  hit merge 3f2a9c1          # Merge the given commit
+  hit merge --conflict diff3 feature      # Include the common ancestor in conflict markers
+  hit merge -X theirs feature             # Settle conflicting hunks with the incoming version
+  hit merge --strategy ours feature       # Record the merge but keep the current tree
+  hit merge --no-ff feature               # Always create a merge commit
+  hit merge --squash feature              # Stage the merged result for a normal commit
+  hit merge --dry-run feature             # Report what would conflict without merging
+  hit merge --abort                       # Give up on a conflicted merge
+  hit merge --continue                    # Commit a merge once its conflicts are resolved`,
 	Args: cobra.MaximumNArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
-		targetBranch, _ := storage.GetBranch()
-		remoteName := "origin"
-		currentBranch := targetBranch
+		currentBranch, _ := storage.GetBranch()
 
-		if hash != "" {
-			if err := repo.MergeCommitHash(currentBranch, hash); err != nil {
+		if mergeAbort && mergeContinue {
+			fmt.Println("Error: --abort and --continue cannot be used together")
+			os.Exit(1)
+		}
+		if mergeAbort {
+			if err := repo.AbortMerge(); err != nil {
 				fmt.Printf("Error: %v\n", err)
 				os.Exit(1)
 			}
-			fmt.Printf("Successfully merged commit '%s' into current branch\n", hash)
+			fmt.Println("Merge aborted")
 			return
 		}
-		if len(args) == 1 {
-			remoteName = args[0]
-		} else if len(args) == 2 {
-			remoteName = args[0]
-			targetBranch = args[1]
+		if mergeContinue {
+			if !repo.IsInMergeState() {
+				fmt.Println("Error: there is no merge in progress")
+				os.Exit(1)
+			}
+			commitHash, err := commit.CreateCommit("", commit.Options{NoVerify: mergeNoVerify, NoEdit: mergeNoEdit})
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Merge completed: %s\n", commitHash)
+			return
 		}
 
-		fmt.Println("Current Branch", currentBranch)
-		fmt.Println("Target Branch", targetBranch)
-		fmt.Println("Remote Name", remoteName)
+		if !cmd.Flags().Changed("conflict") {
+			conflictStyle = config.GetString("merge.conflictStyle", repo.ConflictStyleMerge)
+		}
+		if !cmd.Flags().Changed("no-ff") && !mergeSquash {
+			mergeNoFF = !config.GetBool("merge.ff", true)
+		}
+		if conflictStyle != repo.ConflictStyleMerge && conflictStyle != repo.ConflictStyleDiff3 {
+			fmt.Printf("Error: unknown conflict style '%s' (use merge or diff3)\n", conflictStyle)
+			os.Exit(1)
+		}
+		if mergeStrategy != repo.MergeStrategyRecursive && mergeStrategy != repo.MergeStrategyOurs {
+			fmt.Printf("Error: unknown merge strategy '%s' (use recursive or ours)\n", mergeStrategy)
+			os.Exit(1)
+		}
+		if mergeFavor != "" && mergeFavor != repo.FavorOurs && mergeFavor != repo.FavorTheirs {
+			fmt.Printf("Error: unknown strategy option '%s' (use ours or theirs)\n", mergeFavor)
+			os.Exit(1)
+		}
+		if mergeSquash && mergeNoFF {
+			fmt.Println("Error: --squash and --no-ff cannot be used together")
+			os.Exit(1)
+		}
+		opts := repo.MergeOptions{
+			ConflictStyle: conflictStyle,
+			Strategy:      mergeStrategy,
+			Favor:         mergeFavor,
+			NoFF:          mergeNoFF,
+			Squash:        mergeSquash,
+			NoVerify:      mergeNoVerify,
+		}
 
-		err := repo.MergeBranch(currentBranch, targetBranch, remoteName)
+		// Work out the target once so --dry-run previews what the merge
+		// would do.
+		var target string
+		var merge func() error
+		switch {
+		case hash != "":
+			target = hash
+			merge = func() error { return repo.MergeRevision(currentBranch, hash, opts) }
+		case len(args) == 2:
+			target = args[0] + "/" + args[1]
+			merge = func() error { return repo.MergeBranch(currentBranch, args[1], args[0], opts) }
+		case len(args) == 1 && !repo.RevisionExists(args[0]) && isRemote(args[0]):
+			// "hit merge origin" merges origin/<current branch>
+			target = args[0] + "/" + currentBranch
+			merge = func() error { return repo.MergeBranch(currentBranch, currentBranch, args[0], opts) }
+		case len(args) == 1:
+			target = args[0]
+			merge = func() error { return repo.MergeRevision(currentBranch, args[0], opts) }
+		default:
+			target = "origin/" + currentBranch
+			merge = func() error { return repo.MergeBranch(currentBranch, currentBranch, "origin", opts) }
+		}
+
+		if mergeDryRun {
+			previewMerge(currentBranch, target, opts)
+			return
+		}
+
+		err := merge()
+		if errors.Is(err, repo.ErrMergeConflicts) {
+			fmt.Println("Automatic merge failed; fix conflicts and then run 'hit merge --continue'")
+			os.Exit(1)
+		}
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
 		}
-		fmt.Printf("Successfully merged '%s' into current branch\n", targetBranch)
+		fmt.Printf("Successfully merged '%s' into %s\n", target, currentBranch)
 	},
 }
 
 func init() {
-	mergeCmd.Flags().StringVarP(&hash, "commit", "c", "", "Merge the given commit hash into the current branch")
+	mergeCmd.Flags().StringVarP(&hash, "commit", "c", "", "Merge the given commit into the current branch")
+	mergeCmd.Flags().StringVar(&conflictStyle, "conflict", repo.ConflictStyleMerge, "Conflict marker style: merge or diff3, overriding merge.conflictStyle")
+	mergeCmd.Flags().StringVarP(&mergeStrategy, "strategy", "s", repo.MergeStrategyRecursive, "Merge strategy: recursive or ours")
+	mergeCmd.Flags().StringVarP(&mergeFavor, "strategy-option", "X", "", "Settle conflicting hunks in favour of ours or theirs")
+	mergeCmd.Flags().BoolVar(&mergeNoFF, "no-ff", false, "Create a merge commit even when a fast-forward is possible")
+	mergeCmd.Flags().BoolVar(&mergeSquash, "squash", false, "Stage the merged result without creating a merge commit")
+	mergeCmd.Flags().BoolVar(&mergeAbort, "abort", false, "Abort the merge in progress and restore the pre-merge state")
+	mergeCmd.Flags().BoolVar(&mergeContinue, "continue", false, "Create the merge commit once all conflicts are resolved")
+	mergeCmd.Flags().BoolVar(&mergeDryRun, "dry-run", false, "Report clean, auto-merged and conflicting paths without merging")
+	mergeCmd.Flags().BoolVar(&mergeNoVerify, "no-verify", false, "Skip the pre-merge and commit-msg hooks")
+	mergeCmd.Flags().BoolVar(&mergeNoEdit, "no-edit", false, "With --continue, use the merge message without opening the editor")
 	rootCmd.AddCommand(mergeCmd)
 }
+
+func previewMerge(currentBranch, rev string, opts repo.MergeOptions) {
+	preview, err := repo.PreviewMerge(currentBranch, rev, opts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if preview.UpToDate {
+		fmt.Println("Already up to date.")
+		return
+	}
+	if preview.FastForward {
+		fmt.Printf("Would fast-forward %s to %s\n", currentBranch, preview.Target)
+	} else {
+		fmt.Printf("Would merge '%s' into %s (merge base %s)\n", rev, currentBranch, preview.Base)
+	}
+
+	conflicts := 0
+	for _, f := range preview.Files {
+		if f.Outcome == repo.MergeOutcomeConflict {
+			conflicts++
+			fmt.Printf("  %-12s %s (%s)\n", f.Outcome, f.Path, f.Kind)
+		} else {
+			fmt.Printf("  %-12s %s\n", f.Outcome, f.Path)
+		}
+	}
+
+	if conflicts > 0 {
+		fmt.Printf("%d file(s) would conflict\n", conflicts)
+		os.Exit(1)
+	}
+	fmt.Println("No conflicts")
+}
+
+func isRemote(name string) bool {
+	_, err := repo.GetRemoteURL(name)
+	return err == nil
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/airbornharsh/hit/internal/repo"
+	"github.com/spf13/cobra"
+)
+
+var mergeBaseAll bool
+
+var mergeBaseCmd = &cobra.Command{
+	Use:   "merge-base <commit> <commit>",
+	Short: "Find the best common ancestor of two commits",
+	Long: `Find the best common ancestor of two commits, the one a merge between them uses.
+
+Commits may be given as branch names, remote branches (origin/main), HEAD or hashes.
+Criss-cross histories can have several merge bases; --all prints every one of them.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		commitA, err := repo.ResolveRevision(args[0])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		commitB, err := repo.ResolveRevision(args[1])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		bases, err := repo.MergeBases(commitA, commitB)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(bases) == 0 {
+			os.Exit(1)
+		}
+
+		if !mergeBaseAll {
+			bases = bases[:1]
+		}
+		for _, base := range bases {
+			fmt.Println(base)
+		}
+	},
+}
+
+func init() {
+	mergeBaseCmd.Flags().BoolVar(&mergeBaseAll, "all", false, "Print all merge bases instead of just one")
+	rootCmd.AddCommand(mergeBaseCmd)
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/airbornharsh/hit/internal/config"
+	"github.com/airbornharsh/hit/internal/repo"
+	"github.com/spf13/cobra"
+)
+
+var mergeTool string
+
+var mergetoolCmd = &cobra.Command{
+	Use:   "mergetool [file...]",
+	Short: "Resolve merge conflicts with an external tool",
+	Long: `Run an external merge tool on each unresolved conflict.
+
+The tool command comes from --tool, the merge.tool config key or the
+HIT_MERGETOOL environment variable, in that order, and runs through the shell
+with these variables set:
+  BASE    common ancestor version
+  LOCAL   current branch version
+  REMOTE  incoming version
+  MERGED  the working tree file to write the result to
+
+A file is staged and marked resolved when the tool exits successfully.
+
+Examples:
+  hit mergetool --tool 'meld "$LOCAL" "$MERGED" "$REMOTE"'
+  hit config set --global merge.tool 'meld "$LOCAL" "$MERGED" "$REMOTE"'
+  HIT_MERGETOOL='vimdiff "$LOCAL" "$MERGED" "$REMOTE"' hit mergetool f.txt`,
+	Run: func(cmd *cobra.Command, args []string) {
+		tool := mergeTool
+		if tool == "" {
+			tool = config.GetString("merge.tool", "")
+		}
+		if tool == "" {
+			tool = os.Getenv("HIT_MERGETOOL")
+		}
+		if tool == "" {
+			fmt.Println("Error: no merge tool configured (use --tool, 'hit config set merge.tool <command>' or HIT_MERGETOOL)")
+			os.Exit(1)
+		}
+
+		if err := repo.RunMergeTool(tool, args); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	mergetoolCmd.Flags().StringVarP(&mergeTool, "tool", "t", "", "Merge tool command to run")
+	rootCmd.AddCommand(mergetoolCmd)
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/airbornharsh/hit/internal/repo"
+	"github.com/spf13/cobra"
+)
+
+var mvForce bool
+
+var mvCmd = &cobra.Command{
+	Use:   "mv <source>... <destination>",
+	Short: "Move or rename a file or directory",
+	Long: `Move or rename tracked files and directories, in the working tree and the index.
+
+When the destination is an existing directory the sources are moved into it.
+An existing destination file is only overwritten with -f.
+
+Examples:
+  hit mv old.txt new.txt              # Rename a file
+  hit mv a.txt b.txt docs             # Move files into docs/
+  hit mv src lib                      # Rename a directory`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		sources, dst := args[:len(args)-1], args[len(args)-1]
+		if len(sources) > 1 {
+			if info, err := os.Stat(dst); err != nil || !info.IsDir() {
+				fmt.Printf("Error: destination '%s' is not a directory\n", dst)
+				os.Exit(1)
+			}
+		}
+		for _, src := range sources {
+			if err := repo.MovePath(filepath.Clean(src), dst, mvForce); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+func init() {
+	mvCmd.Flags().BoolVarP(&mvForce, "force", "f", false, "Overwrite an existing destination file")
+	rootCmd.AddCommand(mvCmd)
+}
@@ -10,16 +10,31 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var pushTags bool
+var pushNoVerify bool
+
 var pushCmd = &cobra.Command{
 	Use:   "push",
 	Short: "Push commits to a remote repository",
-	Long:  `Push commits to a remote repository. Use 'hit push' to push the current branch to origin, 'hit push [branch]' to push a specific branch to origin, or 'hit push -u [REMOTENAME] [BRANCH]' to push and set upstream tracking.`,
+	Long:  `Push commits to a remote repository. Use 'hit push' to push the current branch to origin, 'hit push [branch]' to push a specific branch to origin, or 'hit push -u [REMOTENAME] [BRANCH]' to push and set upstream tracking. Use 'hit push --tags [REMOTENAME]' to push all tags.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if _, err := os.Stat(".hit"); os.IsNotExist(err) {
 			fmt.Println("Error: Not a HIT repository")
 			return
 		}
 
+		if pushTags {
+			remoteName := "origin"
+			if len(args) > 0 {
+				remoteName = args[0]
+			}
+			if err := repo.PushTags(remoteName, pushNoVerify); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				return
+			}
+			return
+		}
+
 		var remoteName, branchName string
 
 		if len(args) == 0 {
@@ -54,7 +69,7 @@ var pushCmd = &cobra.Command{
 		fmt.Println("Remote Name", remoteName)
 		fmt.Println("Branch Name", branchName)
 
-		if err := repo.Push(remoteName, branchName); err != nil {
+		if err := repo.Push(remoteName, branchName, pushNoVerify); err != nil {
 			fmt.Printf("Error: %v\n", err)
 			return
 		}
@@ -64,5 +79,7 @@ var pushCmd = &cobra.Command{
 }
 
 func init() {
+	pushCmd.Flags().BoolVar(&pushTags, "tags", false, "Push all tags instead of a branch")
+	pushCmd.Flags().BoolVar(&pushNoVerify, "no-verify", false, "Skip the pre-push hook")
 	rootCmd.AddCommand(pushCmd)
 }
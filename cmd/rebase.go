@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/airbornharsh/hit/internal/repo"
+	"github.com/spf13/cobra"
+)
+
+var rebaseOnto string
+var rebaseContinue bool
+var rebaseSkip bool
+var rebaseAbort bool
+var rebaseInteractive bool
+var rebaseAutosquash bool
+
+var rebaseCmd = &cobra.Command{
+	Use:   "rebase [upstream]",
+	Short: "Replay the current branch's commits on top of another base",
+	Long: `Replay the commits of the current branch that are not in upstream on top of
+upstream, or on top of the --onto base. Each replayed commit becomes a new commit.
+
+With -i the plan is opened in $HIT_EDITOR, $VISUAL or $EDITOR first.
+
+When a commit does not apply cleanly, or an 'edit' step is reached, the rebase stops. Resolve the conflicts,
+stage the files with 'hit add' and run 'hit rebase --continue'.
+
+Examples:
+  hit rebase main                     # Move the current branch onto main
+  hit rebase origin/main              # Rebase onto the remote-tracking branch
+  hit rebase --onto main old-base     # Replay commits after old-base onto main
+  hit rebase -i main                  # Edit the plan: pick, reword, edit, squash, fixup, drop
+  hit rebase -i --autosquash main     # Fold "fixup!" and "squash!" commits into their targets
+  hit rebase --continue               # Carry on after resolving conflicts
+  hit rebase --skip                   # Drop the commit that failed to apply
+  hit rebase --abort                  # Return to the state before the rebase`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+		switch {
+		case rebaseContinue:
+			err = repo.ContinueRebase()
+		case rebaseSkip:
+			err = repo.SkipRebase()
+		case rebaseAbort:
+			err = repo.AbortRebase()
+			if err == nil {
+				fmt.Println("Rebase aborted")
+			}
+		case len(args) == 1:
+			err = repo.Rebase(args[0], repo.RebaseOptions{
+				Onto:        rebaseOnto,
+				Interactive: rebaseInteractive,
+				Autosquash:  rebaseAutosquash,
+			})
+		default:
+			err = fmt.Errorf("no upstream given")
+		}
+		if errors.Is(err, repo.ErrStoppedOnConflicts) {
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rebaseCmd.Flags().StringVar(&rebaseOnto, "onto", "", "Replay the commits onto this base instead of upstream")
+	rebaseCmd.Flags().BoolVarP(&rebaseInteractive, "interactive", "i", false, "Edit the list of commits to replay before starting")
+	rebaseCmd.Flags().BoolVar(&rebaseAutosquash, "autosquash", false, "Move fixup!/squash! commits after the commits they amend")
+	rebaseCmd.Flags().BoolVar(&rebaseContinue, "continue", false, "Continue the rebase after resolving conflicts")
+	rebaseCmd.Flags().BoolVar(&rebaseSkip, "skip", false, "Skip the commit that failed to apply")
+	rebaseCmd.Flags().BoolVar(&rebaseAbort, "abort", false, "Abort the rebase and restore the original branch")
+	rebaseCmd.MarkFlagsMutuallyExclusive("continue", "skip", "abort")
+	rootCmd.AddCommand(rebaseCmd)
+}
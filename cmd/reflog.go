@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/airbornharsh/hit/internal/repo"
+	"github.com/airbornharsh/hit/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var reflogCmd = &cobra.Command{
+	Use:   "reflog [branch]",
+	Short: "Show where a branch has pointed",
+	Long: `Show the recorded updates of a branch, newest first.
+
+Each entry can be used as a revision, e.g. 'hit merge-base main@{2} feature'.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		branch, _ := storage.GetBranch()
+		if len(args) == 1 {
+			branch = args[0]
+		}
+
+		entries, err := repo.ReadReflog(branch)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		for i := len(entries) - 1; i >= 0; i-- {
+			entry := entries[i]
+			fmt.Printf("%s %s@{%d}: %s\n", entry.New[:min(7, len(entry.New))], branch, len(entries)-1-i, entry.Message)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reflogCmd)
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/airbornharsh/hit/internal/repo"
+	"github.com/spf13/cobra"
+)
+
+var rerereCmd = &cobra.Command{
+	Use:   "rerere [enable|disable|clear|status]",
+	Short: "Reuse recorded conflict resolutions",
+	Long: `Record how merge conflicts are resolved and reuse the resolution when the
+same conflict shows up again.
+
+Once enabled, staging a conflicted file with 'hit add' records its resolution.
+Later merges that hit an identical conflict apply it automatically; 'hit status'
+lists those files as resolved from a recorded resolution. Review them, then
+commit as usual.
+
+Examples:
+  hit rerere enable    # Start recording resolutions
+  hit rerere clear     # Forget every recorded resolution
+  hit rerere disable   # Stop recording and forget every resolution`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		action := "status"
+		if len(args) == 1 {
+			action = args[0]
+		}
+
+		var err error
+		switch action {
+		case "enable":
+			err = repo.EnableRerere()
+		case "disable":
+			err = repo.DisableRerere()
+		case "clear":
+			err = repo.ClearRerere()
+		case "status":
+			if repo.RerereEnabled() {
+				fmt.Println("rerere is enabled")
+			} else {
+				fmt.Println("rerere is disabled")
+			}
+		default:
+			err = fmt.Errorf("unknown action '%s' (use enable, disable, clear or status)", action)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rerereCmd)
+}
@@ -9,11 +9,30 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var resetPatch bool
+
 var resetCmd = &cobra.Command{
 	Use:   "reset [file]",
 	Short: "Reset file(s) from staging area",
-	Args:  cobra.MinimumNArgs(1),
+	Long: `Reset file(s) from staging area.
+
+With -p, each staged hunk under the given paths (or the whole repository) is
+shown and you choose whether to unstage it with y, n, s (split), e (edit) or
+q (quit).`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if resetPatch {
+			return nil
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
+		if resetPatch {
+			if err := repo.ResetPatch(args); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
 		for _, file := range args {
 			info, _ := os.Stat(file)
 			pwd, err := os.Getwd()
@@ -40,5 +59,6 @@ var resetCmd = &cobra.Command{
 }
 
 func init() {
+	resetCmd.Flags().BoolVarP(&resetPatch, "patch", "p", false, "Choose hunks to unstage interactively")
 	rootCmd.AddCommand(resetCmd)
 }
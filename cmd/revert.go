@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,11 +11,59 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var revertCommits []string
+var revertMainline int
+var revertContinue bool
+var revertSkip bool
+var revertAbort bool
+var revertPatch bool
+
 var revertCmd = &cobra.Command{
-	Use:   "revert [file]",
-	Short: "Revert file(s) to last commit state",
-	Args:  cobra.MinimumNArgs(1),
+	Use:   "revert [file...] | --commit <commit>",
+	Short: "Revert file(s) to last commit state, or undo a commit",
+	Long: `Revert files in the working tree to their last committed state.
+
+With --commit, record a new commit that undoes the changes an existing commit made
+to its parent. Reverting a merge commit needs -m to say which parent's side to keep.
+When the inverse change does not apply cleanly the revert stops. Resolve the conflicts,
+stage the files with 'hit add' and run 'hit revert --continue'.
+
+With -p, each working tree hunk under the given files is shown and you choose
+whether to discard it with y, n, s (split), e (edit) or q (quit).
+
+Examples:
+  hit revert f.txt                    # Discard working tree changes to f.txt
+  hit revert .                        # Discard all working tree changes
+  hit revert -p f.txt                 # Pick the hunks of f.txt to discard
+  hit revert --commit a1b2c3d         # Undo commit a1b2c3d with a new commit
+  hit revert -c HEAD -c a1b2c3d       # Undo several commits in order
+  hit revert -c a1b2c3d -m 1          # Undo a merge, keeping its first parent
+  hit revert --continue               # Carry on after resolving conflicts
+  hit revert --abort                  # Return to the state before the revert`,
 	Run: func(cmd *cobra.Command, args []string) {
+		if revertContinue || revertSkip || revertAbort || len(revertCommits) > 0 {
+			err := revertCommitsCmd(args)
+			if errors.Is(err, repo.ErrStoppedOnConflicts) {
+				os.Exit(1)
+			}
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+		if revertPatch {
+			if err := repo.RevertPatch(args); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+		if len(args) == 0 {
+			fmt.Println("Error: nothing to revert; give files or --commit <commit>")
+			os.Exit(1)
+		}
+
 		for _, file := range args {
 			pwd, err := os.Getwd()
 			if err != nil {
@@ -52,6 +101,34 @@ var revertCmd = &cobra.Command{
 	},
 }
 
+func revertCommitsCmd(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("files cannot be given together with --commit, --continue, --skip or --abort")
+	}
+	switch {
+	case revertContinue:
+		return repo.ContinueRevert()
+	case revertSkip:
+		return repo.SkipRevert()
+	case revertAbort:
+		if err := repo.AbortRevert(); err != nil {
+			return err
+		}
+		fmt.Println("Revert aborted")
+		return nil
+	default:
+		return repo.Revert(revertCommits, repo.RevertOptions{Mainline: revertMainline})
+	}
+}
+
 func init() {
+	revertCmd.Flags().StringSliceVarP(&revertCommits, "commit", "c", nil, "Commit to undo with a new commit (repeatable)")
+	revertCmd.Flags().IntVarP(&revertMainline, "mainline", "m", 0, "Parent number (1 or 2) to keep when reverting a merge")
+	revertCmd.Flags().BoolVar(&revertContinue, "continue", false, "Continue after resolving conflicts")
+	revertCmd.Flags().BoolVar(&revertSkip, "skip", false, "Skip the commit that failed to revert")
+	revertCmd.Flags().BoolVar(&revertAbort, "abort", false, "Abort and restore the original branch")
+	revertCmd.Flags().BoolVarP(&revertPatch, "patch", "p", false, "Choose working tree hunks to discard interactively")
+	revertCmd.MarkFlagsMutuallyExclusive("continue", "skip", "abort")
+	revertCmd.MarkFlagsMutuallyExclusive("patch", "commit")
 	rootCmd.AddCommand(revertCmd)
 }
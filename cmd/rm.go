@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/airbornharsh/hit/internal/repo"
+	"github.com/spf13/cobra"
+)
+
+var rmCached bool
+var rmRecursive bool
+var rmForce bool
+
+var rmCmd = &cobra.Command{
+	Use:   "rm <path>...",
+	Short: "Remove files from the working tree and the index",
+	Long: `Remove tracked files from the index and the working tree.
+
+Files whose working tree or staged content differs from HEAD are kept unless -f
+is given, so uncommitted work is not lost. With --cached the files stay in the
+working tree and only stop being tracked.
+
+Examples:
+  hit rm old.txt                      # Delete old.txt and stage the deletion
+  hit rm --cached secrets.env         # Stop tracking a file but keep it
+  hit rm -r build                     # Remove a directory
+  hit rm -f notes.txt                 # Remove even with local changes`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := repo.RemovePaths(args, repo.RemoveOptions{
+			Cached:    rmCached,
+			Recursive: rmRecursive,
+			Force:     rmForce,
+		})
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rmCmd.Flags().BoolVar(&rmCached, "cached", false, "Only remove from the index, keeping the working tree files")
+	rmCmd.Flags().BoolVarP(&rmRecursive, "recursive", "r", false, "Allow removing directories")
+	rmCmd.Flags().BoolVarP(&rmForce, "force", "f", false, "Remove files even when they have uncommitted changes")
+	rootCmd.AddCommand(rmCmd)
+}
@@ -28,8 +28,7 @@ func init() {
 }
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		println(err)
-		os.Exit(1)
-	}
+	aliases := configuredAliases()
+	addAliasCommands(aliases)
+	dispatch(os.Args[1:], aliases)
 }
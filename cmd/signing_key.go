@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"os"
+
+	"github.com/airbornharsh/hit/internal/repo"
+	"github.com/airbornharsh/hit/internal/signing"
+	"github.com/spf13/cobra"
+)
+
+var signingKeyCmd = &cobra.Command{
+	Use:   "signing-key [generate|show]",
+	Short: "Manage the key used to sign commits and tags",
+	Long: `Manage the ed25519 key used by 'hit commit -S' and 'hit tag -s'.
+
+The key is read from $HIT_SIGNING_KEY, the user.signingKey config, or the
+default location used by 'generate'. Both PKCS#8 PEM keys and unencrypted OpenSSH ed25519 keys
+(ssh-keygen -t ed25519) work. 'show' prints the public key in the form used
+by allowed signers files.
+
+Examples:
+  hit signing-key generate
+  echo "me@example.com $(hit signing-key show)" >> .hit/allowed_signers
+  HIT_SIGNING_KEY=~/.ssh/id_ed25519 hit commit -S -m "Signed change"`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		action := "show"
+		if len(args) == 1 {
+			action = args[0]
+		}
+
+		path, err := repo.SigningKeyPath()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		switch action {
+		case "generate":
+			pub, err := signing.GenerateKey(path)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Created signing key %s\n", path)
+			fmt.Println(signing.PublicKeyString(pub))
+		case "show":
+			key, err := signing.LoadPrivateKey(path)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println(signing.PublicKeyString(key.Public().(ed25519.PublicKey)))
+		default:
+			fmt.Printf("Error: unknown action '%s' (use generate or show)\n", action)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(signingKeyCmd)
+}
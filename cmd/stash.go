@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/airbornharsh/hit/internal/repo"
+	"github.com/airbornharsh/hit/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var stashMessage string
+var stashUntracked bool
+var stashPatch bool
+var stashIndex bool
+
+var stashCmd = &cobra.Command{
+	Use:   "stash",
+	Short: "Set aside working changes and restore them later",
+	Long: `Save the staged and unstaged changes to tracked files, and optionally untracked
+files, then reset the working tree and index to HEAD. Stashes are referred to as
+stash@{0} (the latest), stash@{1} and so on, or simply by number.
+
+Examples:
+  hit stash                           # Same as 'hit stash push'
+  hit stash push -m "half-done fix"   # Stash with a description
+  hit stash push -u                   # Include untracked files
+  hit stash list                      # List the stashes
+  hit stash show -p stash@{1}         # Show the changes in a stash
+  hit stash apply                     # Reapply the latest stash and keep it
+  hit stash pop                       # Reapply the latest stash and drop it
+  hit stash drop 1                    # Remove stash@{1}`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		stashPushCmd.Run(cmd, args)
+	},
+}
+
+var stashPushCmd = &cobra.Command{
+	Use:   "push",
+	Short: "Save local changes to a new stash and reset to HEAD",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := repo.StashPush(stashMessage, stashUntracked); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var stashListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the stashes",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := repo.ListStashes()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		for i, entry := range entries {
+			fmt.Printf("stash@{%d}: %s\n", i, entry.Message)
+		}
+	},
+}
+
+var stashShowCmd = &cobra.Command{
+	Use:   "show [stash]",
+	Short: "Show the changes recorded in a stash",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		n := stashArg(args)
+		_, changes, err := repo.StashChanges(n)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, change := range changes {
+			if !stashPatch {
+				fmt.Printf("%s %s\n", change.Status, change.Path)
+				continue
+			}
+			kind := map[string]string{"A": "added", "M": "modified", "D": "deleted", "?": "untracked"}[change.Status]
+			fmt.Printf("diff -- %s (%s)\n", change.Path, kind)
+			if diff := storage.GetDifference(change.Old, change.New); diff != "" {
+				fmt.Println(diff)
+			}
+		}
+	},
+}
+
+var stashApplyCmd = &cobra.Command{
+	Use:   "apply [stash]",
+	Short: "Reapply a stash and keep it in the list",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		applyStash(stashArg(args), false)
+	},
+}
+
+var stashPopCmd = &cobra.Command{
+	Use:   "pop [stash]",
+	Short: "Reapply a stash and drop it from the list",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		applyStash(stashArg(args), true)
+	},
+}
+
+var stashDropCmd = &cobra.Command{
+	Use:   "drop [stash]",
+	Short: "Remove a stash from the list",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		n := stashArg(args)
+		entry, err := repo.StashDrop(n)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Dropped stash@{%d} (%s)\n", n, entry.Message)
+	},
+}
+
+var stashClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove every stash",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := repo.StashClear(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func stashArg(args []string) int {
+	ref := ""
+	if len(args) == 1 {
+		ref = args[0]
+	}
+	n, err := repo.ParseStashRef(ref)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	return n
+}
+
+func applyStash(n int, drop bool) {
+	conflicted, err := repo.StashApply(n, stashIndex)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if conflicted {
+		fmt.Println("Stashed changes conflict with the working tree.")
+		fmt.Println("Resolve the conflicts and stage them with 'hit add', or run 'hit merge --abort' to undo.")
+		fmt.Println("The stash entry is kept in case you need it again.")
+		os.Exit(1)
+	}
+	if drop {
+		entry, err := repo.StashDrop(n)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Dropped stash@{%d} (%s)\n", n, entry.Message)
+	}
+}
+
+func init() {
+	for _, c := range []*cobra.Command{stashCmd, stashPushCmd} {
+		c.Flags().StringVarP(&stashMessage, "message", "m", "", "Description of the stash")
+		c.Flags().BoolVarP(&stashUntracked, "include-untracked", "u", false, "Also stash untracked files")
+	}
+	stashShowCmd.Flags().BoolVarP(&stashPatch, "patch", "p", false, "Show the diff of each file")
+	stashApplyCmd.Flags().BoolVar(&stashIndex, "index", false, "Restore the staged state as well")
+	stashPopCmd.Flags().BoolVar(&stashIndex, "index", false, "Restore the staged state as well")
+	stashCmd.AddCommand(stashPushCmd, stashListCmd, stashShowCmd, stashApplyCmd, stashPopCmd, stashDropCmd, stashClearCmd)
+	rootCmd.AddCommand(stashCmd)
+}
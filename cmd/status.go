@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/airbornharsh/hit/internal/go_types"
 	"github.com/airbornharsh/hit/internal/repo"
@@ -39,7 +40,13 @@ func showStatus() error {
 	// Check for merge conflicts first
 	conflictResolution, err := repo.LoadConflictResolution()
 	if err == nil && conflictResolution != nil && len(conflictResolution.Conflicts) > 0 {
-		if conflictResolution.IsMergeState {
+		operation := repo.SequencerOperation()
+		if repo.IsRebaseInProgress() {
+			operation = "rebase"
+		}
+		if operation != "" {
+			fmt.Printf("%s in progress - resolving conflicts:\n", strings.ToUpper(operation[:1])+operation[1:])
+		} else if conflictResolution.IsMergeState {
 			fmt.Println("In merge state - resolving conflicts:")
 		} else {
 			fmt.Println("Merge conflicts detected:")
@@ -50,15 +57,28 @@ func showStatus() error {
 			if conflict.Status == "resolved" {
 				status = "✅ Resolved"
 			}
-			fmt.Printf("  %s %s\n", status, conflict.FilePath)
+			if conflict.Rerere {
+				status = "✅ Resolved (recorded resolution)"
+			}
+			if conflict.Kind != "" && conflict.Kind != repo.ConflictKindContent {
+				fmt.Printf("  %s (%s) %s\n", status, conflict.Kind, conflict.FilePath)
+			} else {
+				fmt.Printf("  %s %s\n", status, conflict.FilePath)
+			}
 		}
 
 		if conflictResolution.HasUnresolvedConflicts() {
 			fmt.Println("\nTo resolve conflicts:")
 			fmt.Println("1. Edit the conflicted files manually")
 			fmt.Println("2. Remove conflict markers (<<<<<<<, =======, >>>>>>>)")
-			fmt.Println("3. Run 'hit add <file>' to stage resolved files")
-			fmt.Println("4. Run 'hit commit' to complete the merge")
+			fmt.Println("3. Run 'hit add <file>' to stage resolved files (delete the file first to accept a deletion)")
+			if operation != "" {
+				fmt.Printf("4. Run 'hit %s --continue' (or --skip / --abort)\n", operation)
+			} else {
+				fmt.Println("4. Run 'hit commit' to complete the merge")
+			}
+		} else if operation != "" {
+			fmt.Printf("\nAll conflicts resolved. Run 'hit %s --continue' to carry on.\n", operation)
 		} else {
 			fmt.Println("\nAll conflicts resolved. Run 'hit commit' to complete the merge.")
 		}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/airbornharsh/hit/internal/repo"
+	"github.com/spf13/cobra"
+)
+
+var tagAnnotate bool
+var tagMessage string
+var tagDelete bool
+var tagList bool
+var tagForce bool
+var tagShowMessage bool
+var tagSign bool
+
+var tagCmd = &cobra.Command{
+	Use:   "tag [name [commit]]",
+	Short: "Create, list or delete tags",
+	Long: `Create, list or delete tags in refs/tags.
+
+A plain tag is a lightweight name for a commit. With -a, or -m, an annotated tag
+object is created that records the tagger, the date and a message; -s signs it. Without
+arguments, or with -l, the tags are listed, filtered by an optional glob pattern.
+
+Examples:
+  hit tag                             # List all tags
+  hit tag -l 'v1.*'                   # List tags matching a pattern
+  hit tag v1.0                        # Tag HEAD
+  hit tag v0.9 a1b2c3d                # Tag an older commit
+  hit tag -a v1.0 -m "First release"  # Create an annotated tag
+  hit tag -s v1.0 -m "First release"  # Create a signed annotated tag
+  hit tag -d v1.0                     # Delete a tag
+  hit push --tags                     # Share tags with origin`,
+	Run: func(cmd *cobra.Command, args []string) {
+		switch {
+		case tagDelete:
+			if len(args) == 0 {
+				fmt.Println("Error: no tag name given")
+				os.Exit(1)
+			}
+			for _, name := range args {
+				ref, err := repo.DeleteTag(name)
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+				fmt.Printf("Deleted tag '%s' (was %s)\n", name, ref[:min(7, len(ref))])
+			}
+		case tagList || len(args) == 0:
+			pattern := ""
+			if len(args) > 0 {
+				pattern = args[0]
+			}
+			listTags(pattern)
+		default:
+			if len(args) > 2 {
+				fmt.Println("Error: too many arguments")
+				os.Exit(1)
+			}
+			rev := ""
+			if len(args) == 2 {
+				rev = args[1]
+			}
+			annotate := tagAnnotate || tagMessage != ""
+			if err := repo.CreateTag(args[0], rev, tagMessage, annotate, tagSign, tagForce); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+func listTags(pattern string) {
+	tags, err := repo.ListTags(pattern)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, tag := range tags {
+		if !tagShowMessage {
+			fmt.Println(tag.Name)
+			continue
+		}
+		line := ""
+		if tag.Annotation != nil {
+			line, _, _ = strings.Cut(tag.Annotation.Message, "\n")
+		}
+		fmt.Printf("%-15s %s\n", tag.Name, line)
+	}
+}
+
+func init() {
+	tagCmd.Flags().BoolVarP(&tagAnnotate, "annotate", "a", false, "Create an annotated tag object")
+	tagCmd.Flags().StringVarP(&tagMessage, "message", "m", "", "Tag message (implies -a)")
+	tagCmd.Flags().BoolVarP(&tagSign, "sign", "s", false, "Create a signed annotated tag")
+	tagCmd.Flags().BoolVarP(&tagDelete, "delete", "d", false, "Delete the named tags")
+	tagCmd.Flags().BoolVarP(&tagList, "list", "l", false, "List tags, optionally matching a pattern")
+	tagCmd.Flags().BoolVarP(&tagForce, "force", "f", false, "Replace an existing tag")
+	tagCmd.Flags().BoolVarP(&tagShowMessage, "lines", "n", false, "Show the first line of each annotated tag's message")
+	rootCmd.AddCommand(tagCmd)
+}
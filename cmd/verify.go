@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/airbornharsh/hit/internal/repo"
+	"github.com/spf13/cobra"
+)
+
+var verifyCommitCmd = &cobra.Command{
+	Use:   "verify-commit <commit>...",
+	Short: "Check the signatures of commits",
+	Long: `Check the SSH signature stored in each commit against the allowed signers file,
+$HIT_ALLOWED_SIGNERS or .hit/allowed_signers, which uses the OpenSSH format:
+
+  alice@example.com ssh-ed25519 AAAAC3Nza...
+
+Exits with status 1 if any commit is unsigned, has a bad signature or was signed
+by a key that is not listed.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		failed := false
+		for _, rev := range args {
+			_, status, err := repo.VerifyCommit(rev)
+			if !reportSignature(rev, status, err) {
+				failed = true
+			}
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+var verifyTagCmd = &cobra.Command{
+	Use:   "verify-tag <tag>...",
+	Short: "Check the signatures of annotated tags",
+	Long: `Check the SSH signature of each annotated tag against the allowed signers file,
+$HIT_ALLOWED_SIGNERS or .hit/allowed_signers.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		failed := false
+		for _, name := range args {
+			_, status, err := repo.VerifyTag(name)
+			if !reportSignature(name, status, err) {
+				failed = true
+			}
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+// reportSignature prints the outcome of a signature check and reports
+// whether it passed.
+func reportSignature(name string, status *repo.SignatureStatus, err error) bool {
+	switch {
+	case status == nil:
+		fmt.Printf("error: %s: %v\n", name, err)
+		return false
+	case err != nil:
+		fmt.Printf("error: %s: signature by %s could not be checked: %v\n", name, status.Fingerprint, err)
+		return false
+	case !status.Good():
+		fmt.Printf("error: %s: valid signature with key %s, but no principal matched in allowed signers\n", name, status.Fingerprint)
+		return false
+	}
+	fmt.Printf("%s: Good signature from \"%s\" with key %s\n", name, status.Principal, status.Fingerprint)
+	return true
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCommitCmd)
+	rootCmd.AddCommand(verifyTagCmd)
+}
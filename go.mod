@@ -2,10 +2,13 @@ module github.com/airbornharsh/hit
 
 go 1.25.2
 
+require (
+	github.com/sergi/go-diff v1.4.0
+	github.com/spf13/cobra v1.10.1
+)
+
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/joho/godotenv v1.5.1 // indirect
-	github.com/sergi/go-diff v1.4.0 // indirect
-	github.com/spf13/cobra v1.10.1 // indirect
 	github.com/spf13/pflag v1.0.9 // indirect
 )
@@ -0,0 +1,48 @@
+package apis
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/airbornharsh/hit/internal/go_types"
+	"github.com/airbornharsh/hit/utils"
+)
+
+func PushTags(remote string, tags []go_types.TagRef) error {
+	url := fmt.Sprintf(utils.BACKEND_URL+"/api/v1/repo/tags?remote=%s", remote)
+
+	token := utils.GetSession().Token
+
+	jsonBody, err := json.Marshal(map[string]any{
+		"tags": tags,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Terminal %s", token))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to push tags: %s", resp.Status)
+		}
+		return fmt.Errorf("failed to push tags: %s", string(body))
+	}
+
+	return nil
+}
@@ -2,18 +2,34 @@ package commit
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/airbornharsh/hit/internal/go_types"
 	"github.com/airbornharsh/hit/internal/repo"
 	"github.com/airbornharsh/hit/internal/storage"
 )
 
-func CreateCommit(message string) (string, error) {
+// Options controls how CreateCommit records a commit.
+type Options struct {
+	Sign     bool   // sign the commit with the configured signing key
+	Amend    bool   // replace the tip of the branch instead of adding a commit
+	Author   string // "Name <email>" overriding the configured author
+	Date     string // author date overriding the current time
+	NoVerify bool   // skip the pre-commit and commit-msg hooks
+	NoEdit   bool   // use the merge or amended message without opening the editor
+}
+
+func CreateCommit(message string, opts Options) (string, error) {
+	if opts.Amend {
+		return amendCommit(message, opts)
+	}
+
 	// Check for unresolved merge conflicts
 	conflictResolution, err := repo.LoadConflictResolution()
 	var remoteCommits []go_types.Commit
@@ -32,14 +48,40 @@ func CreateCommit(message string) (string, error) {
 		if len(conflictResolution.RemoteCommits) > 0 {
 			remoteCommits = append(remoteCommits, conflictResolution.RemoteCommits...)
 		}
-		if conflictResolution.Message != "" {
-			message = conflictResolution.Message
+	}
+
+	if !opts.NoVerify {
+		if err := repo.RunHook(repo.HookPreCommit, ""); err != nil {
+			return "", err
 		}
 	}
 
-	if message == "" {
+	// Without -m the message is written in the editor, starting from the
+	// merge message when there is one.
+	edit := message == "" && !opts.NoEdit
+	var source []string
+	switch {
+	case message != "":
+		source = []string{"message"}
+	case conflictResolution != nil && conflictResolution.Message != "":
+		message = conflictResolution.Message
+		source = []string{"merge"}
+	case !edit:
 		return "", fmt.Errorf("cannot commit: no message provided")
 	}
+	message, err = repo.PrepareCommitMessage(message, edit, opts.NoVerify, source...)
+	if err != nil {
+		return "", err
+	}
+
+	author, err := repo.AuthorIdentity()
+	if err != nil {
+		return "", err
+	}
+	author, err = repo.OverrideAuthor(author, opts.Author, opts.Date)
+	if err != nil {
+		return "", err
+	}
 
 	stagedTreeHash, err := repo.BuildTreeFromStage()
 	if err != nil {
@@ -73,8 +115,14 @@ func CreateCommit(message string) (string, error) {
 		Parent:      parent,
 		OtherParent: otherParent,
 		Message:     message,
-		Author:      os.Getenv("USER"),
-		Timestamp:   go_types.TimeNow(),
+	}
+	if err := repo.StampCommit(&commit, author); err != nil {
+		return "", err
+	}
+	if opts.Sign {
+		if err := repo.SignCommit(&commit); err != nil {
+			return "", fmt.Errorf("failed to sign commit: %v", err)
+		}
 	}
 
 	var commits []go_types.Commit
@@ -90,9 +138,9 @@ func CreateCommit(message string) (string, error) {
 	}
 
 	slices.SortFunc(commits, func(a, b go_types.Commit) int {
-		if a.Timestamp.Before(b.Timestamp) {
+		if a.CommittedAt().Before(b.CommittedAt()) {
 			return -1
-		} else if a.Timestamp.After(b.Timestamp) {
+		} else if a.CommittedAt().After(b.CommittedAt()) {
 			return 1
 		}
 		return 0
@@ -105,22 +153,68 @@ func CreateCommit(message string) (string, error) {
 		return "", err
 	}
 
-	err = os.WriteFile(filepath.Join(".hit", "refs", "heads", currentBranch), []byte(stagedTreeHash), 0644)
+	err = os.WriteFile(filepath.Join(".hit", "refs", "heads", currentBranch), []byte(commit.Hash), 0644)
 	if err != nil {
 		return "", err
 	}
 
-	// Clear conflict resolution if this was a merge commit
-	if repo.IsInMergeState() {
+	reflogMessage := "commit: " + message
+	if otherParent != "" {
+		reflogMessage = "commit (merge): " + message
+	}
+	if err := repo.AppendReflog(currentBranch, parent, commit.Hash, reflogMessage); err != nil {
+		fmt.Printf("Warning: failed to update reflog: %v\n", err)
+	}
+
+	// Clear conflict resolution once the merge (or squash) is committed
+	if conflictResolution != nil {
 		if err := repo.ClearConflictResolution(); err != nil {
 			fmt.Printf("Warning: failed to clear conflict resolution: %v\n", err)
 		}
 	}
 
-	return stagedTreeHash, nil
+	repo.RunPostCommitHook()
+	return commit.Hash, nil
+}
+
+// amendCommit replaces the tip of the current branch, running the same hooks
+// as a new commit.
+func amendCommit(message string, opts Options) (string, error) {
+	// Refuse before the hooks and the editor run, not after.
+	if cr, _ := repo.LoadConflictResolution(); cr != nil {
+		return "", fmt.Errorf("cannot amend: you have unfinished merge or conflict state")
+	}
+	head, err := repo.HeadCommit()
+	if err != nil {
+		return "", fmt.Errorf("nothing to amend: %v", err)
+	}
+	if !opts.NoVerify {
+		if err := repo.RunHook(repo.HookPreCommit, ""); err != nil {
+			return "", err
+		}
+	}
+	edit := message == "" && !opts.NoEdit
+	if message == "" {
+		message = head.Message
+	}
+	message, err = repo.PrepareCommitMessage(message, edit, opts.NoVerify, "commit", head.Hash)
+	if err != nil {
+		return "", err
+	}
+
+	amended, err := repo.AmendHead(message, opts.Author, opts.Date, opts.Sign)
+	if err != nil {
+		return "", err
+	}
+	repo.RunPostCommitHook()
+	return amended.Hash, nil
 }
 
-func LogCommits() {
+const dateFormat = "Mon Jan 2 15:04:05 2006 -0700"
+
+// LogCommits prints the log of the current branch. With showSignature the
+// signature of each signed commit is checked and reported.
+func LogCommits(showSignature bool) {
 	head, err := storage.GetHead()
 	if err != nil {
 		fmt.Println("Error getting HEAD:", err)
@@ -153,12 +247,34 @@ func LogCommits() {
 		commit := commits[i]
 
 		fmt.Printf("commit %s\n", commit.Hash)
+		if showSignature {
+			printSignature(commit)
+		}
 		fmt.Printf("Author: %s\n", commit.Author)
-		fmt.Printf("Date:   %s\n", commit.Timestamp.Format("Mon Jan 2 15:04:05 2006 -0700"))
+		fmt.Printf("Date:   %s\n", commit.Timestamp.Format(dateFormat))
+		if commit.Committer != "" && (commit.Committer != commit.Author || commit.CommitDate.Sub(commit.Timestamp).Abs() >= time.Second) {
+			fmt.Printf("Commit: %s\n", commit.Committer)
+			fmt.Printf("        %s\n", commit.CommitDate.Format(dateFormat))
+		}
 		fmt.Printf("\n    %s\n\n", commit.Message)
 	}
 }
 
+func printSignature(c go_types.Commit) {
+	status, err := repo.VerifyCommitSignature(c)
+	switch {
+	case errors.Is(err, repo.ErrNoSignature):
+	case status == nil && err != nil:
+		fmt.Printf("%s\n", err)
+	case err != nil:
+		fmt.Printf("Signature by %s could not be checked: %v\n", status.Fingerprint, err)
+	case status.Good():
+		fmt.Printf("Good signature from \"%s\" with key %s\n", status.Principal, status.Fingerprint)
+	default:
+		fmt.Printf("Valid signature with key %s, but no principal matched in allowed signers\n", status.Fingerprint)
+	}
+}
+
 func ShowCommit(hash string) {
 	commitData, err := storage.LoadObject(hash)
 	if err != nil {
@@ -0,0 +1,391 @@
+// Package config reads and writes hit's layered configuration. Settings are
+// read from the system file, the user's ~/.hitconfig and the repository's
+// .hit/config, with later files overriding earlier ones.
+//
+// Each file is a JSON object of sections. A key "section.name" is stored as
+// {"section": {"name": value}}, and a key with a subsection such as
+// "remote.origin.url" as {"remotes": {"origin": {"url": value}}}.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Scope is one configuration file.
+type Scope int
+
+const (
+	System Scope = iota
+	Global
+	Local
+)
+
+// Scopes lists every scope from lowest to highest precedence.
+var Scopes = []Scope{System, Global, Local}
+
+func (s Scope) String() string {
+	switch s {
+	case System:
+		return "system"
+	case Global:
+		return "global"
+	default:
+		return "local"
+	}
+}
+
+// Path returns the file for scope. $HIT_CONFIG_SYSTEM and $HIT_CONFIG_GLOBAL
+// replace the system and global files.
+func Path(scope Scope) (string, error) {
+	switch scope {
+	case System:
+		if path := os.Getenv("HIT_CONFIG_SYSTEM"); path != "" {
+			return path, nil
+		}
+		return "/etc/hitconfig", nil
+	case Global:
+		if path := os.Getenv("HIT_CONFIG_GLOBAL"); path != "" {
+			return path, nil
+		}
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, ".hitconfig"), nil
+	default:
+		return filepath.Join(".hit", "config"), nil
+	}
+}
+
+// sectionKeys maps sections to the JSON keys they are stored under, for
+// sections whose layout predates this package.
+var sectionKeys = map[string]string{
+	"remote": "remotes",
+}
+
+// splitKey splits key into section, subsection and name. The subsection is
+// empty for two-part keys.
+func splitKey(key string) (section, subsection, name string, err error) {
+	parts := strings.Split(key, ".")
+	if len(parts) < 2 || slices.Contains(parts, "") {
+		return "", "", "", fmt.Errorf("invalid key '%s', expected section.name", key)
+	}
+	section, name = parts[0], parts[len(parts)-1]
+	subsection = strings.Join(parts[1:len(parts)-1], ".")
+	return section, subsection, name, nil
+}
+
+func jsonPath(key string) ([]string, error) {
+	section, subsection, name, err := splitKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if stored, ok := sectionKeys[section]; ok {
+		section = stored
+	}
+	if subsection == "" {
+		return []string{section, name}, nil
+	}
+	return []string{section, subsection, name}, nil
+}
+
+// Entry is a key and its value as read from one scope.
+type Entry struct {
+	Key   string
+	Value string
+	Scope Scope
+}
+
+// File is one configuration file.
+type File struct {
+	Scope Scope
+	path  string
+	data  map[string]any
+}
+
+// Load reads the file for scope. A missing file is empty.
+func Load(scope Scope) (*File, error) {
+	path, err := Path(scope)
+	if err != nil {
+		return nil, err
+	}
+	f := &File{Scope: scope, path: path, data: map[string]any{}}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &f.data); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	if f.data == nil {
+		f.data = map[string]any{}
+	}
+	return f, nil
+}
+
+// Save writes the file back.
+func (f *File) Save() error {
+	if f.Scope == Local {
+		if _, err := os.Stat(filepath.Dir(f.path)); err != nil {
+			return fmt.Errorf("not a hit repository")
+		}
+	}
+	data, err := json.MarshalIndent(f.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %v", err)
+	}
+	return os.WriteFile(f.path, append(data, '\n'), 0644)
+}
+
+// lookup walks the JSON objects along path.
+func (f *File) lookup(path []string) (any, bool) {
+	var value any = f.data
+	for _, p := range path {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[p]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// Get returns the value of key in this file.
+func (f *File) Get(key string) (string, bool) {
+	path, err := jsonPath(key)
+	if err != nil {
+		return "", false
+	}
+	value, ok := f.lookup(path)
+	if !ok {
+		return "", false
+	}
+	return formatValue(value)
+}
+
+// Set validates value against key's type and stores it.
+func (f *File) Set(key, value string) error {
+	k, ok := Lookup(key)
+	if !ok {
+		return fmt.Errorf("unknown config key '%s'", key)
+	}
+	typed, err := k.parse(value)
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %v", key, err)
+	}
+	path, err := jsonPath(key)
+	if err != nil {
+		return err
+	}
+
+	object := f.data
+	for _, p := range path[:len(path)-1] {
+		child, ok := object[p].(map[string]any)
+		if !ok {
+			child = map[string]any{}
+			object[p] = child
+		}
+		object = child
+	}
+	object[path[len(path)-1]] = typed
+	return nil
+}
+
+// Unset removes key, and any section it leaves empty.
+func (f *File) Unset(key string) error {
+	path, err := jsonPath(key)
+	if err != nil {
+		return err
+	}
+	if _, ok := f.Get(key); !ok {
+		return fmt.Errorf("%s is not set in the %s config", key, f.Scope)
+	}
+	for i := len(path); i > 0; i-- {
+		parent, _ := f.lookup(path[:i-1])
+		object := parent.(map[string]any)
+		if child, ok := object[path[i-1]].(map[string]any); ok && len(child) > 0 {
+			break
+		}
+		delete(object, path[i-1])
+	}
+	return nil
+}
+
+// RemoveSubsection removes every key in section.subsection, such as a
+// remote. It reports whether there was anything to remove.
+func (f *File) RemoveSubsection(section, subsection string) bool {
+	path, _ := jsonPath(section + "." + subsection + ".x")
+	parent, ok := f.lookup(path[:1])
+	if !ok {
+		return false
+	}
+	object, ok := parent.(map[string]any)
+	if !ok {
+		return false
+	}
+	if _, ok := object[subsection]; !ok {
+		return false
+	}
+	delete(object, subsection)
+	return true
+}
+
+// Subsections lists the subsections of section set in this file.
+func (f *File) Subsections(section string) []string {
+	path, _ := jsonPath(section + ".x.x")
+	value, _ := f.lookup(path[:1])
+	object, _ := value.(map[string]any)
+	var names []string
+	for name, child := range object {
+		if _, ok := child.(map[string]any); ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Entries lists every key set in this file, sorted by key.
+func (f *File) Entries() []Entry {
+	var entries []Entry
+	for _, stored := range slices.Sorted(maps.Keys(f.data)) {
+		section := stored
+		for name, key := range sectionKeys {
+			if key == stored {
+				section = name
+			}
+		}
+		object, ok := f.data[stored].(map[string]any)
+		if !ok {
+			continue
+		}
+		for _, name := range slices.Sorted(maps.Keys(object)) {
+			child, ok := object[name].(map[string]any)
+			if !ok {
+				if value, ok := formatValue(object[name]); ok {
+					entries = append(entries, Entry{section + "." + name, value, f.Scope})
+				}
+				continue
+			}
+			for _, field := range slices.Sorted(maps.Keys(child)) {
+				// Remotes also record their own name, which the key
+				// already holds.
+				if stored == "remotes" && field == "name" {
+					continue
+				}
+				if value, ok := formatValue(child[field]); ok {
+					entries = append(entries, Entry{section + "." + name + "." + field, value, f.Scope})
+				}
+			}
+		}
+	}
+	return entries
+}
+
+func formatValue(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+// loadAll reads every scope, lowest precedence first. Files that cannot be
+// read are skipped.
+func loadAll() []*File {
+	var files []*File
+	for _, scope := range Scopes {
+		if f, err := Load(scope); err == nil {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// Get returns the value of key from the highest precedence file that sets
+// it.
+func Get(key string) (string, bool) {
+	files := loadAll()
+	for i := len(files) - 1; i >= 0; i-- {
+		if value, ok := files[i].Get(key); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// GetString returns key's value, or def when it is not set.
+func GetString(key, def string) string {
+	if value, ok := Get(key); ok {
+		return value
+	}
+	return def
+}
+
+// GetBool returns key as a boolean, or def when it is unset or invalid.
+func GetBool(key string, def bool) bool {
+	value, ok := Get(key)
+	if !ok {
+		return def
+	}
+	b, err := parseBool(value)
+	if err != nil {
+		return def
+	}
+	return b
+}
+
+// GetInt returns key as an integer, or def when it is unset or invalid.
+func GetInt(key string, def int) int {
+	value, ok := Get(key)
+	if !ok {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// Subsections lists the subsections of section across every file.
+func Subsections(section string) []string {
+	var names []string
+	for _, f := range loadAll() {
+		names = append(names, f.Subsections(section)...)
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// All lists the effective value of every key, sorted by key, with the scope
+// it comes from.
+func All() []Entry {
+	effective := map[string]Entry{}
+	for _, f := range loadAll() {
+		for _, entry := range f.Entries() {
+			effective[entry.Key] = entry
+		}
+	}
+	entries := slices.Collect(maps.Values(effective))
+	slices.SortFunc(entries, func(a, b Entry) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return entries
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Type is the type of a configuration value.
+type Type int
+
+const (
+	String Type = iota
+	Bool
+	Int
+)
+
+func (t Type) String() string {
+	switch t {
+	case Bool:
+		return "bool"
+	case Int:
+		return "int"
+	default:
+		return "string"
+	}
+}
+
+// Key describes a configuration key. Name may contain a "*" standing for a
+// subsection or a user-chosen name, as in "remote.*.url".
+type Key struct {
+	Name   string
+	Type   Type
+	Values []string // allowed values of a string key; empty allows any
+	Usage  string
+}
+
+// Keys lists every key hit understands.
+var Keys = []Key{
+	{Name: "user.name", Type: String, Usage: "Name recorded as author, committer and tagger"},
+	{Name: "user.email", Type: String, Usage: "Email recorded as author, committer and tagger"},
+	{Name: "user.signingKey", Type: String, Usage: "Private key used by commit -S and tag -s"},
+	{Name: "signing.allowedSigners", Type: String, Usage: "Allowed signers file used to verify signatures"},
+	{Name: "core.editor", Type: String, Usage: "Editor for commit, tag and rebase messages"},
+	{Name: "commit.template", Type: String, Usage: "File whose contents start a new commit message"},
+	{Name: "color.ui", Type: Bool, Usage: "Colour diff output"},
+	{Name: "diff.context", Type: Int, Usage: "Unchanged lines shown around each change"},
+	{Name: "merge.conflictStyle", Type: String, Values: []string{"merge", "diff3"}, Usage: "Conflict marker style"},
+	{Name: "merge.ff", Type: Bool, Usage: "Fast-forward merges when possible; false always creates a merge commit"},
+	{Name: "merge.tool", Type: String, Usage: "Command hit mergetool runs when --tool is not given"},
+	{Name: "remote.*.url", Type: String, Usage: "URL of a remote"},
+	{Name: "alias.*", Type: String, Usage: "Command an alias expands to; a leading ! runs it in the shell"},
+}
+
+// Lookup finds the key matching name.
+func Lookup(name string) (*Key, bool) {
+	for i := range Keys {
+		if keyMatches(Keys[i].Name, name) {
+			return &Keys[i], true
+		}
+	}
+	return nil, false
+}
+
+// keyMatches reports whether name fits pattern. A "*" matches one or more
+// dot separated parts, so subsections may contain dots.
+func keyMatches(pattern, name string) bool {
+	before, after, wildcard := strings.Cut(pattern, "*")
+	if !wildcard {
+		return pattern == name
+	}
+	rest, ok := strings.CutPrefix(name, before)
+	if !ok {
+		return false
+	}
+	middle, ok := strings.CutSuffix(rest, after)
+	return ok && middle != ""
+}
+
+// parse converts value to the JSON value stored for k.
+func (k *Key) parse(value string) (any, error) {
+	switch k.Type {
+	case Bool:
+		return parseBool(value)
+	case Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not an integer", value)
+		}
+		return n, nil
+	default:
+		if len(k.Values) > 0 && !slices.Contains(k.Values, value) {
+			return nil, fmt.Errorf("'%s' is not one of %s", value, strings.Join(k.Values, ", "))
+		}
+		return value, nil
+	}
+}
+
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "true", "yes", "on", "1":
+		return true, nil
+	case "false", "no", "off", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("'%s' is not a boolean", value)
+}
@@ -63,9 +63,19 @@ type CloneRepositoryApiBody struct {
 				Message     string `json:"message"`
 				Author      string `json:"author"`
 				Timestamp   string `json:"timestamp"`
+				Committer   string `json:"committer,omitempty"`
+				CommitDate  string `json:"commitDate,omitempty"`
 			} `json:"commits"`
 		} `json:"branches"`
+		Tags       []TagRef       `json:"tags"`
 		Config     map[string]any `json:"config"`
 		HeadBranch string         `json:"headBranch"`
 	} `json:"data"`
 }
+
+// TagRef is a tag as exchanged with the remote: Hash is the commit for a
+// lightweight tag or the tag object for an annotated one.
+type TagRef struct {
+	Name string `json:"name"`
+	Hash string `json:"hash"`
+}
@@ -17,8 +17,9 @@ type Index struct {
 }
 
 type Tree struct {
-	Entries map[string]string `json:"entries"` // file path -> object hash
-	Parent  string            `json:"parent"`
+	Entries   map[string]string `json:"entries"` // file path -> object hash
+	Parent    string            `json:"parent"`
+	Signature string            `json:"signature,omitempty"` // SSH signature of the commit
 }
 
 type Commit struct {
@@ -27,9 +28,37 @@ type Commit struct {
 	OtherParent string    `json:"otherParent"`
 	Message     string    `json:"message"`
 	Author      string    `json:"author"`
-	Timestamp   time.Time `json:"timestamp"`
+	Timestamp   time.Time `json:"timestamp"` // author date
+	Committer   string    `json:"committer,omitempty"`
+	CommitDate  time.Time `json:"commitDate,omitzero"`
+}
+
+// Tag is an annotated tag object. Object is the tagged commit.
+type Tag struct {
+	Object    string    `json:"object"`
+	Tag       string    `json:"tag"`
+	Tagger    string    `json:"tagger"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+	Signature string    `json:"signature,omitempty"`
+}
+
+// CommittedAt is when c was committed. Commits recorded before committers
+// were tracked only have the author date.
+func (c Commit) CommittedAt() time.Time {
+	if c.CommitDate.IsZero() {
+		return c.Timestamp
+	}
+	return c.CommitDate
 }
 
 func TimeNow() time.Time {
 	return time.Now().UTC()
 }
+
+type ReflogEntry struct {
+	Old       string    `json:"old"`
+	New       string    `json:"new"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+}
@@ -0,0 +1,95 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/airbornharsh/hit/internal/go_types"
+	"github.com/airbornharsh/hit/internal/storage"
+)
+
+// AmendHead replaces the tip of the current branch with a commit of the
+// current index that keeps the tip's parents. An empty message keeps the
+// old one; author ("Name <email>") and date replace the tip's author and
+// author date when given. The old tip stays in the reflog; a warning is printed
+// when it has already been pushed.
+func AmendHead(message, author, date string, sign bool) (*go_types.Commit, error) {
+	if cr, _ := LoadConflictResolution(); cr != nil {
+		return nil, fmt.Errorf("cannot amend: you have unfinished merge or conflict state")
+	}
+
+	branch, err := storage.GetBranch()
+	if err != nil {
+		return nil, err
+	}
+	head, err := branchHeadCommit(branch)
+	if err != nil {
+		return nil, fmt.Errorf("nothing to amend: %v", err)
+	}
+	if message == "" {
+		message = head.Message
+	}
+	amendAuthor, err := OverrideAuthor(CommitAuthor(*head), author, date)
+	if err != nil {
+		return nil, err
+	}
+
+	pushed, err := remotesContaining(branch, head.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	amended, err := amendCommit(branch, readIndexEntries(), message, amendAuthor, sign, "commit (amend): "+firstLine(message))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pushed) > 0 {
+		fmt.Printf("warning: the amended commit %s was already pushed to %v;\n", shortHash(head.Hash), pushed)
+		fmt.Println("warning: pushing this branch again rewrites published history")
+	}
+	return amended, nil
+}
+
+// HeadCommit returns the commit at the tip of the current branch.
+func HeadCommit() (*go_types.Commit, error) {
+	branch, err := storage.GetBranch()
+	if err != nil {
+		return nil, err
+	}
+	return branchHeadCommit(branch)
+}
+
+// remotesContaining lists the remotes whose copy of branch contains commit.
+func remotesContaining(branch, commit string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(".hit", "refs", "remotes"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	graph, err := loadCommitGraph()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load commit graph: %v", err)
+	}
+
+	var remotes []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		remoteHead, err := getRemoteBranchCommit(entry.Name(), branch)
+		if err != nil {
+			continue
+		}
+		if reachableCommits(graph, remoteHead)[commit] {
+			remotes = append(remotes, entry.Name())
+		}
+	}
+	slices.Sort(remotes)
+	return remotes, nil
+}
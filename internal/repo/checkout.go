@@ -55,6 +55,7 @@ func CreateBranch(branch string) error {
 		return fmt.Errorf("failed to update HEAD: %v", err)
 	}
 
+	runPostHook(HookPostCheckout, currentCommitHash, currentCommitHash, "1")
 	return nil
 }
 
@@ -76,6 +77,7 @@ func CreateBranchAt(branch string, commitHash string) error {
 	if err := json.Unmarshal([]byte(treeData), &tree); err != nil {
 		return fmt.Errorf("failed to parse tree object for %s: %v", commitHash, err)
 	}
+	previousCommit := currentHeadCommit()
 	copyLogsForNewBranch(branch, commitHash)
 
 	newBranchRefPath := filepath.Join(".hit", "refs", "heads", branch)
@@ -92,6 +94,7 @@ func CreateBranchAt(branch string, commitHash string) error {
 		return fmt.Errorf("failed to update working directory and index: %v", err)
 	}
 
+	runPostHook(HookPostCheckout, previousCommit, commitHash, "1")
 	return nil
 }
 
@@ -154,9 +157,9 @@ func copyLogsForNewBranch(newBranch string, commitHash string) {
 		newCommits := extractCommit(commitHash)
 
 		slices.SortFunc(newCommits, func(a, b go_types.Commit) int {
-			if a.Timestamp.Before(b.Timestamp) {
+			if a.CommittedAt().Before(b.CommittedAt()) {
 				return -1
-			} else if a.Timestamp.After(b.Timestamp) {
+			} else if a.CommittedAt().After(b.CommittedAt()) {
 				return 1
 			}
 			return 0
@@ -214,6 +217,7 @@ func SwitchBranch(branch string) error {
 		return fmt.Errorf("you have uncommitted changes. Please commit or stash them before switching branches")
 	}
 
+	previousCommit := currentHeadCommit()
 	headPath := filepath.Join(".hit", "HEAD")
 	newHead := fmt.Sprintf("ref: refs/heads/%s", branch)
 	err = os.WriteFile(headPath, []byte(newHead), 0644)
@@ -251,6 +255,7 @@ func SwitchBranch(branch string) error {
 		return fmt.Errorf("failed to update index: %v", err)
 	}
 
+	runPostHook(HookPostCheckout, previousCommit, commitHash, "1")
 	return nil
 }
 
@@ -0,0 +1,116 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/airbornharsh/hit/internal/storage"
+)
+
+// CheckoutConflictSide resolves a conflicted file by taking one side of the
+// merge, FavorOurs or FavorTheirs. The chosen version is written to the
+// working tree and staged; a side where the file was deleted stages the
+// deletion.
+func CheckoutConflictSide(filePath, side string) error {
+	cr, conflict, err := loadFileConflict(filePath)
+	if err != nil {
+		return err
+	}
+
+	hash := conflict.CurrentHash
+	if side == FavorTheirs {
+		hash = conflict.TargetHash
+	}
+
+	if hash == "" {
+		if err := os.Remove(conflict.FilePath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %v", conflict.FilePath, err)
+		}
+	} else if err := storage.RestoreFileFromObject(conflict.FilePath, hash); err != nil {
+		return err
+	}
+	if err := setIndexEntry(conflict.FilePath, hash); err != nil {
+		return err
+	}
+
+	if conflict.Status != "resolved" {
+		cr.MarkResolved(conflict.FilePath)
+	}
+	return cr.SaveConflictResolution()
+}
+
+// RecreateConflict puts the conflict markers of a file back into the working
+// tree, unstages any resolution and marks the file as conflicted again.
+func RecreateConflict(filePath string) error {
+	cr, conflict, err := loadFileConflict(filePath)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(conflict.FilePath), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(conflict.FilePath, []byte(conflict.Content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", conflict.FilePath, err)
+	}
+
+	// Unstage back to what the index held before the merge.
+	preMerge := conflict.CurrentHash
+	if cr.OrigIndex != nil {
+		preMerge = cr.OrigIndex.Entries[conflict.FilePath]
+	}
+	if err := setIndexEntry(conflict.FilePath, preMerge); err != nil {
+		return err
+	}
+
+	for i := range cr.Conflicts {
+		if cr.Conflicts[i].FilePath == conflict.FilePath {
+			cr.Conflicts[i].Status = "conflict"
+			cr.Conflicts[i].Rerere = false
+		}
+	}
+	cr.Resolved = slices.DeleteFunc(cr.Resolved, func(p string) bool { return p == conflict.FilePath })
+	return cr.SaveConflictResolution()
+}
+
+// loadFileConflict finds the conflict recorded for filePath, given relative
+// to the current directory.
+func loadFileConflict(filePath string) (*ConflictResolution, *ConflictFile, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	relPath, err := getRelativePath(absPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cr, err := LoadConflictResolution()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load conflict resolution: %v", err)
+	}
+	if cr == nil {
+		return nil, nil, fmt.Errorf("no merge in progress")
+	}
+
+	for i := range cr.Conflicts {
+		if cr.Conflicts[i].FilePath == relPath {
+			return cr, &cr.Conflicts[i], nil
+		}
+	}
+	return nil, nil, fmt.Errorf("path '%s' is not in conflict", relPath)
+}
+
+// setIndexEntry points the index entry of relPath at hash, removing the
+// entry when hash is empty.
+func setIndexEntry(relPath, hash string) error {
+	entries := readIndexEntries()
+	if hash == "" {
+		delete(entries, relPath)
+	} else {
+		entries[relPath] = hash
+	}
+	return writeIndexEntries(entries)
+}
@@ -122,6 +122,11 @@ func CloneRepository(url string) error {
 		return fmt.Errorf("failed to restore files: %v", err)
 	}
 
+	err = saveFetchedTags(cloneRepositoryApiBody.Data.Tags)
+	if err != nil {
+		return fmt.Errorf("failed to create tags: %v", err)
+	}
+
 	return nil
 }
 
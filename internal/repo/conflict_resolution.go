@@ -8,16 +8,40 @@ import (
 
 	"github.com/airbornharsh/hit/internal/go_types"
 	"github.com/airbornharsh/hit/internal/storage"
-	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// Conflict kinds recorded in ConflictFile.Kind
+const (
+	ConflictKindContent      = "content"       // both sides changed the same lines
+	ConflictKindModifyDelete = "modify/delete" // current modified, target deleted
+	ConflictKindDeleteModify = "delete/modify" // current deleted, target modified
+	ConflictKindAddAdd       = "add/add"       // both sides added different files
+)
+
+// Conflict marker styles accepted by MergeOptions.ConflictStyle
+const (
+	ConflictStyleMerge = "merge"
+	ConflictStyleDiff3 = "diff3"
 )
 
 type ConflictFile struct {
 	FilePath     string `json:"filePath"`
+	Kind         string `json:"kind"`
 	CurrentHash  string `json:"currentHash"`
 	TargetHash   string `json:"targetHash"`
 	AncestorHash string `json:"ancestorHash"`
 	Content      string `json:"content"`
 	Status       string `json:"status"`
+	Rerere       bool   `json:"rerere,omitempty"` // resolved from a recorded resolution
+}
+
+// ConflictMarkers names the sides written into conflict markers. The base
+// section is only written when ShowBase is set (diff3 style).
+type ConflictMarkers struct {
+	Current  string
+	Base     string
+	Target   string
+	ShowBase bool
 }
 
 type ConflictResolution struct {
@@ -28,9 +52,14 @@ type ConflictResolution struct {
 	IsMergeState  bool              `json:"isMergeState"`
 	Message       string            `json:"message"`
 	RemoteCommits []go_types.Commit `json:"remoteCommits"`
+	OrigIndex     *go_types.Index   `json:"origIndex,omitempty"` // index before the merge started
+	OrigFiles     map[string]string `json:"origFiles,omitempty"` // path -> pre-merge blob hash, "" if absent
 }
 
-func PerformAdvancedFileMerge(currentHash, targetHash string) (string, string, bool, error) {
+// PerformAdvancedFileMerge merges two versions of a file against their common
+// ancestor and stores the result as an object. The returned bool reports
+// whether the merged content contains conflict markers.
+func PerformAdvancedFileMerge(currentHash, targetHash, ancestorHash string, markers ConflictMarkers, favor string) (string, string, bool, error) {
 	currentContent, err := storage.GetFileContentFromHash(currentHash)
 	if err != nil {
 		return "", "", false, fmt.Errorf("failed to get current content: %v", err)
@@ -41,9 +70,14 @@ func PerformAdvancedFileMerge(currentHash, targetHash string) (string, string, b
 		return "", "", false, fmt.Errorf("failed to get target content: %v", err)
 	}
 
-	mergedContent, _, err := PerformLineByLineMerge(currentContent, targetContent)
+	ancestorContent, err := storage.GetFileContentFromHash(ancestorHash)
 	if err != nil {
-		return "", "", false, fmt.Errorf("failed to perform line-by-line merge: %v", err)
+		return "", "", false, fmt.Errorf("failed to get ancestor content: %v", err)
+	}
+
+	mergedContent, hasConflicts, err := PerformThreeWayLineMerge(ancestorContent, currentContent, targetContent, markers, favor)
+	if err != nil {
+		return "", "", false, fmt.Errorf("failed to perform three-way line merge: %v", err)
 	}
 
 	contentBytes := []byte(mergedContent)
@@ -53,81 +87,72 @@ func PerformAdvancedFileMerge(currentHash, targetHash string) (string, string, b
 		return "", "", false, fmt.Errorf("failed to store merged content: %v", err)
 	}
 
-	return contentHash, mergedContent, false, nil
+	return contentHash, mergedContent, hasConflicts, nil
 }
 
-func PerformLineByLineMerge(current, target string) (string, bool, error) {
-	dmp := diffmatchpatch.New()
-	a, b, lineArray := dmp.DiffLinesToChars(current, target)
-	diffs := dmp.DiffMain(a, b, false)
-	diffs = dmp.DiffCharsToLines(diffs, lineArray)
-
-	hasChanges := false
-	for _, d := range diffs {
-		if d.Type == diffmatchpatch.DiffDelete || d.Type == diffmatchpatch.DiffInsert {
-			hasChanges = true
-		}
-	}
-
-	if !hasChanges {
+// PerformThreeWayLineMerge merges current and target line by line against
+// ancestor. Non-overlapping changes are combined; overlapping changes that
+// differ are settled in favour of one side when favor is FavorOurs or
+// FavorTheirs, and written out between conflict markers otherwise.
+func PerformThreeWayLineMerge(ancestor, current, target string, markers ConflictMarkers, favor string) (string, bool, error) {
+	if current == target || target == ancestor {
 		return current, false, nil
 	}
-
-	if canAutoMerge(diffs) {
-		mergedContent := autoMergeChanges(diffs)
-		return mergedContent, false, nil
+	if current == ancestor {
+		return target, false, nil
 	}
 
-	conflictContent := createConflictMarkers(diffs)
-	return conflictContent, true, nil
-}
-
-func canAutoMerge(diff []diffmatchpatch.Diff) bool {
-	hasInsertions := false
-	hasDeletions := false
-
-	for _, d := range diff {
-		switch d.Type {
-		case diffmatchpatch.DiffInsert:
-			hasInsertions = true
-		case diffmatchpatch.DiffDelete:
-			hasDeletions = true
+	chunks := mergeLines(splitLines(ancestor), splitLines(current), splitLines(target))
+	chunks = favorSide(chunks, favor)
+	if !hasConflictChunks(chunks) {
+		var result strings.Builder
+		for _, c := range chunks {
+			result.WriteString(strings.Join(c.Lines, ""))
 		}
+		return result.String(), false, nil
 	}
 
-	return !(hasInsertions && hasDeletions)
+	return createConflictMarkers(chunks, markers), true, nil
 }
 
-func autoMergeChanges(diff []diffmatchpatch.Diff) string {
+// createConflictMarkers renders the whole merged file, writing every
+// conflicting region between markers labelled with the branch names.
+func createConflictMarkers(chunks []mergeChunk, markers ConflictMarkers) string {
 	var result strings.Builder
+	writeSide := func(lines []string) {
+		text := strings.Join(lines, "")
+		result.WriteString(text)
+		if text != "" && !strings.HasSuffix(text, "\n") {
+			result.WriteString("\n")
+		}
+	}
 
-	for _, d := range diff {
-		switch d.Type {
-		case diffmatchpatch.DiffEqual:
-			result.WriteString(d.Text)
-		case diffmatchpatch.DiffInsert:
-			result.WriteString(d.Text)
-		case diffmatchpatch.DiffDelete:
+	for _, c := range chunks {
+		if !c.Conflict {
+			result.WriteString(strings.Join(c.Lines, ""))
 			continue
 		}
+		if s := result.String(); s != "" && !strings.HasSuffix(s, "\n") {
+			result.WriteString("\n")
+		}
+		result.WriteString(conflictMarkerLine("<<<<<<<", markers.Current))
+		writeSide(c.Ours)
+		if markers.ShowBase {
+			result.WriteString(conflictMarkerLine("|||||||", markers.Base))
+			writeSide(c.Base)
+		}
+		result.WriteString("=======\n")
+		writeSide(c.Theirs)
+		result.WriteString(conflictMarkerLine(">>>>>>>", markers.Target))
 	}
-
 	return result.String()
 }
 
-func createConflictMarkers(diffs []diffmatchpatch.Diff) string {
-	var result strings.Builder
-	for i := 0; i < len(diffs)-1; i++ {
-		if diffs[i].Type == diffmatchpatch.DiffDelete && diffs[i+1].Type == diffmatchpatch.DiffInsert {
-			result.WriteString("<<<<<<< HEAD (Current Branch)\n")
-			result.WriteString(strings.TrimSpace(diffs[i].Text))
-			result.WriteString("\n=======\n")
-			result.WriteString(strings.TrimSpace(diffs[i+1].Text))
-			result.WriteString("\n>>>>>>> Target Branch\n")
-			i++
-		}
+func conflictMarkerLine(marker, label string) string {
+	if label == "" {
+		return marker + "\n"
 	}
-	return result.String()
+	return marker + " " + label + "\n"
 }
 
 // CreateConflictResolution creates a new conflict resolution session
@@ -154,9 +179,10 @@ func CreateMergeConflictResolution(parent, otherParent, message string) *Conflic
 }
 
 // AddConflict adds a file to the conflict list
-func (cr *ConflictResolution) AddConflict(filePath, currentHash, targetHash, ancestorHash string, content string) {
+func (cr *ConflictResolution) AddConflict(filePath, kind, currentHash, targetHash, ancestorHash string, content string) {
 	conflict := ConflictFile{
 		FilePath:     filePath,
+		Kind:         kind,
 		CurrentHash:  currentHash,
 		TargetHash:   targetHash,
 		AncestorHash: ancestorHash,
@@ -0,0 +1,213 @@
+package repo
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// lineHunk describes one contiguous change against a base file: the base
+// lines in [BaseStart, BaseEnd) are replaced by Lines.
+type lineHunk struct {
+	BaseStart int
+	BaseEnd   int
+	Lines     []string
+}
+
+// mergeChunk is one region of a three-way merge result. Stable chunks carry
+// their merged Lines, conflicting chunks keep every side of the region.
+type mergeChunk struct {
+	Conflict bool
+	Lines    []string
+	Ours     []string
+	Base     []string
+	Theirs   []string
+}
+
+// splitLines splits content into lines, keeping the trailing newline on
+// every line so that joining the result reproduces the input exactly.
+func splitLines(content string) []string {
+	if content == "" {
+		return []string{}
+	}
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLineHunks returns the changes needed to turn base into other.
+func diffLineHunks(base, other []string) []lineHunk {
+	dmp := diffmatchpatch.New()
+	a, b, _ := dmp.DiffLinesToRunes(strings.Join(base, ""), strings.Join(other, ""))
+	diffs := dmp.DiffMainRunes(a, b, false)
+
+	var hunks []lineHunk
+	var current *lineHunk
+	basePos := 0
+	otherPos := 0
+
+	for _, d := range diffs {
+		count := utf8.RuneCountInString(d.Text)
+		switch d.Type {
+		case diffmatchpatch.DiffEqual:
+			if current != nil {
+				hunks = append(hunks, *current)
+				current = nil
+			}
+			basePos += count
+			otherPos += count
+		case diffmatchpatch.DiffDelete:
+			if current == nil {
+				current = &lineHunk{BaseStart: basePos, BaseEnd: basePos}
+			}
+			basePos += count
+			current.BaseEnd = basePos
+		case diffmatchpatch.DiffInsert:
+			if current == nil {
+				current = &lineHunk{BaseStart: basePos, BaseEnd: basePos}
+			}
+			current.Lines = append(current.Lines, other[otherPos:otherPos+count]...)
+			otherPos += count
+		}
+	}
+	if current != nil {
+		hunks = append(hunks, *current)
+	}
+
+	return hunks
+}
+
+// applyHunks rebuilds the [start, end) range of base with the given hunks
+// applied. Every hunk must lie inside the range.
+func applyHunks(base []string, hunks []lineHunk, start, end int) []string {
+	result := []string{}
+	pos := start
+	for _, h := range hunks {
+		result = append(result, base[pos:h.BaseStart]...)
+		result = append(result, h.Lines...)
+		pos = h.BaseEnd
+	}
+	result = append(result, base[pos:end]...)
+	return result
+}
+
+// mergeLines performs a diff3-style merge of ours and theirs against their
+// common base. Changes made by only one side are taken as-is, identical
+// changes on both sides are taken once and only overlapping (or touching)
+// changes that differ are reported as conflicts.
+func mergeLines(base, ours, theirs []string) []mergeChunk {
+	oursHunks := diffLineHunks(base, ours)
+	theirsHunks := diffLineHunks(base, theirs)
+
+	var chunks []mergeChunk
+	appendStable := func(lines []string) {
+		if len(lines) == 0 {
+			return
+		}
+		if n := len(chunks); n > 0 && !chunks[n-1].Conflict {
+			chunks[n-1].Lines = append(chunks[n-1].Lines, lines...)
+			return
+		}
+		chunks = append(chunks, mergeChunk{Lines: slices.Clone(lines)})
+	}
+
+	pos := 0
+	i, j := 0, 0
+	for i < len(oursHunks) || j < len(theirsHunks) {
+		// Start a region at whichever hunk comes first in the base.
+		var start, end int
+		if j >= len(theirsHunks) || (i < len(oursHunks) && oursHunks[i].BaseStart <= theirsHunks[j].BaseStart) {
+			start, end = oursHunks[i].BaseStart, oursHunks[i].BaseEnd
+		} else {
+			start, end = theirsHunks[j].BaseStart, theirsHunks[j].BaseEnd
+		}
+
+		// Grow the region until no hunk from either side overlaps or touches it.
+		oi, tj := i, j
+		for {
+			grown := false
+			for oi < len(oursHunks) && oursHunks[oi].BaseStart <= end {
+				end = max(end, oursHunks[oi].BaseEnd)
+				oi++
+				grown = true
+			}
+			for tj < len(theirsHunks) && theirsHunks[tj].BaseStart <= end {
+				end = max(end, theirsHunks[tj].BaseEnd)
+				tj++
+				grown = true
+			}
+			if !grown {
+				break
+			}
+		}
+
+		appendStable(base[pos:start])
+
+		oursRegion := oursHunks[i:oi]
+		theirsRegion := theirsHunks[j:tj]
+		switch {
+		case len(theirsRegion) == 0:
+			appendStable(applyHunks(base, oursRegion, start, end))
+		case len(oursRegion) == 0:
+			appendStable(applyHunks(base, theirsRegion, start, end))
+		default:
+			oursLines := applyHunks(base, oursRegion, start, end)
+			theirsLines := applyHunks(base, theirsRegion, start, end)
+			if slices.Equal(oursLines, theirsLines) {
+				appendStable(oursLines)
+			} else {
+				chunks = append(chunks, mergeChunk{
+					Conflict: true,
+					Ours:     oursLines,
+					Base:     slices.Clone(base[start:end]),
+					Theirs:   theirsLines,
+				})
+			}
+		}
+
+		pos = end
+		i, j = oi, tj
+	}
+	appendStable(base[pos:])
+
+	return chunks
+}
+
+// hasConflictChunks reports whether any chunk of a merge result conflicts.
+func hasConflictChunks(chunks []mergeChunk) bool {
+	for _, c := range chunks {
+		if c.Conflict {
+			return true
+		}
+	}
+	return false
+}
+
+// favorSide settles every conflicting chunk by taking one side: FavorOurs
+// keeps the current lines, FavorTheirs the target lines. Any other value
+// leaves the conflicts in place.
+func favorSide(chunks []mergeChunk, favor string) []mergeChunk {
+	if favor != FavorOurs && favor != FavorTheirs {
+		return chunks
+	}
+	resolved := make([]mergeChunk, 0, len(chunks))
+	for _, c := range chunks {
+		if c.Conflict {
+			lines := c.Ours
+			if favor == FavorTheirs {
+				lines = c.Theirs
+			}
+			c = mergeChunk{Lines: lines}
+		}
+		if n := len(resolved); n > 0 && !resolved[n-1].Conflict && !c.Conflict {
+			resolved[n-1].Lines = append(resolved[n-1].Lines, c.Lines...)
+			continue
+		}
+		resolved = append(resolved, c)
+	}
+	return resolved
+}
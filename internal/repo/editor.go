@@ -0,0 +1,175 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/airbornharsh/hit/internal/config"
+	"github.com/airbornharsh/hit/internal/storage"
+	"github.com/airbornharsh/hit/utils"
+)
+
+// editMessage lets the user edit a commit message in their editor, starting
+// from initial. Lines starting with '#' are dropped; an empty result is an
+// error.
+func editMessage(initial string) (string, error) {
+	return editText("COMMIT_EDITMSG", initial, commitMessageHelp, "commit")
+}
+
+const commitMessageHelp = "# Please enter the commit message for your changes. Lines starting\n" +
+	"# with '#' will be ignored, and an empty message aborts the commit.\n"
+
+// PrepareCommitMessage produces the message for a commit through
+// .hit/COMMIT_EDITMSG. The file starts as message, then the
+// prepare-commit-msg hook runs with source. With no message or source, the
+// commit.template file is used and the source is "template". With edit set, the staged
+// changes are listed as comments and the file is opened in the editor, and
+// comments are stripped afterwards. The commit-msg hook runs last unless
+// noVerify is set. An empty result, or an unedited template, aborts.
+func PrepareCommitMessage(message string, edit, noVerify bool, source ...string) (string, error) {
+	template := ""
+	if message == "" && len(source) == 0 {
+		var err error
+		if template, err = commitTemplate(); err != nil {
+			return "", err
+		}
+		if template != "" {
+			message = template
+			source = []string{"template"}
+		}
+	}
+
+	path := filepath.Join(".hit", "COMMIT_EDITMSG")
+	content := message + "\n"
+	if edit {
+		content = message + "\n\n" + commitMessageHelp + commitStatusComment()
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %v", path, err)
+	}
+
+	if err := RunHook(HookPrepareCommitMsg, "", append([]string{path}, source...)...); err != nil {
+		return "", err
+	}
+	if edit {
+		if err := utils.LaunchEditor(path); err != nil {
+			return "", err
+		}
+	}
+	if !noVerify {
+		if err := RunHook(HookCommitMsg, "", path); err != nil {
+			return "", err
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	message = strings.TrimSpace(string(data))
+	if edit {
+		message = stripComments(message)
+	}
+	if message == "" {
+		return "", fmt.Errorf("aborting commit due to empty commit message")
+	}
+	if template != "" && message == stripComments(template) {
+		return "", fmt.Errorf("aborting commit; you did not edit the message from the template")
+	}
+	return message, nil
+}
+
+// commitTemplate reads the file named by commit.template, if any.
+func commitTemplate() (string, error) {
+	path, ok := config.Get("commit.template")
+	if !ok {
+		return "", nil
+	}
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		path = filepath.Join(utils.HomeDir, rest)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read commit template: %v", err)
+	}
+	return strings.TrimRight(string(data), "\n"), nil
+}
+
+// commitStatusComment lists the current branch and the staged changes as
+// comment lines.
+func commitStatusComment() string {
+	var b strings.Builder
+	b.WriteString("#\n")
+	if branch, err := storage.GetBranch(); err == nil {
+		fmt.Fprintf(&b, "# On branch %s\n", branch)
+	}
+
+	head := map[string]string{}
+	if tree, err := storage.GetCommitTree(currentHeadCommit()); err == nil {
+		head = tree.Entries
+	}
+	index := readIndexEntries()
+	var lines []string
+	for path, hash := range index {
+		if old, ok := head[path]; !ok {
+			lines = append(lines, "#\tnew file:   "+path)
+		} else if old != hash {
+			lines = append(lines, "#\tmodified:   "+path)
+		}
+	}
+	for path := range head {
+		if _, ok := index[path]; !ok {
+			lines = append(lines, "#\tdeleted:    "+path)
+		}
+	}
+	slices.SortFunc(lines, func(a, b string) int {
+		return strings.Compare(a[14:], b[14:])
+	})
+
+	if len(lines) == 0 {
+		b.WriteString("# No changes staged\n")
+	} else {
+		b.WriteString("# Changes to be committed:\n")
+		b.WriteString(strings.Join(lines, "\n") + "\n")
+	}
+	return b.String()
+}
+
+// editText opens .hit/<file> holding initial followed by the help comment
+// in the user's editor and returns the text without comments.
+func editText(file, initial, help, what string) (string, error) {
+	path := filepath.Join(".hit", file)
+	content := initial + "\n\n" + help
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	if err := utils.LaunchEditor(path); err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	message := stripComments(string(data))
+	if message == "" {
+		return "", fmt.Errorf("aborting due to empty %s message", what)
+	}
+	return message, nil
+}
+
+// stripComments removes '#' lines and surrounding blank lines from text
+// edited by the user.
+func stripComments(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, " \t\r"))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
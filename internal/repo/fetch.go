@@ -47,6 +47,11 @@ func FetchRemote(remoteName string) error {
 		return fmt.Errorf("failed to fetch remote objects: %v", err)
 	}
 
+	err = saveFetchedTags(cloneData.Data.Tags)
+	if err != nil {
+		return fmt.Errorf("failed to fetch remote tags: %v", err)
+	}
+
 	fmt.Printf("Fetched %d branches and %d objects from remote '%s'\n",
 		len(cloneData.Data.Branches), len(cloneData.Data.Hashes), remoteName)
 
@@ -78,6 +83,8 @@ func fetchRemoteBranches(remoteName string, branches []struct {
 		Message     string `json:"message"`
 		Author      string `json:"author"`
 		Timestamp   string `json:"timestamp"`
+		Committer   string `json:"committer,omitempty"`
+		CommitDate  string `json:"commitDate,omitempty"`
 	} `json:"commits"`
 }) error {
 	for _, branch := range branches {
@@ -0,0 +1,112 @@
+package repo
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	"github.com/airbornharsh/hit/internal/storage"
+)
+
+// Hooks are executables in .hit/hooks, run from the top of the repository
+// with these arguments:
+//
+//	pre-commit                     before a commit; non-zero aborts it
+//	prepare-commit-msg <file> <source> [<commit>]
+//	                               may edit the message file; source is
+//	                               message, template, merge or commit (amend,
+//	                               with the amended commit), or absent when
+//	                               the message starts empty; non-zero aborts
+//	commit-msg <file>              may edit or reject the message file
+//	post-commit                    after a commit
+//	pre-merge <commit>             before merging commit; non-zero aborts
+//	post-merge <squash>            after a merge completes; squash is 1 or 0
+//	post-checkout <old> <new> 1    after switching branches
+//	pre-push <remote> <url>        before pushing; stdin has a line
+//	                               "<local ref> <local hash> <remote ref> <remote hash>"
+//	                               per ref; non-zero aborts the push
+//
+// --no-verify skips pre-commit, commit-msg, pre-merge and pre-push.
+const (
+	HookPreCommit        = "pre-commit"
+	HookPrepareCommitMsg = "prepare-commit-msg"
+	HookCommitMsg        = "commit-msg"
+	HookPostCommit       = "post-commit"
+	HookPreMerge         = "pre-merge"
+	HookPostMerge        = "post-merge"
+	HookPostCheckout     = "post-checkout"
+	HookPrePush          = "pre-push"
+)
+
+func hookPath(name string) string {
+	return filepath.Join(".hit", "hooks", name)
+}
+
+// RunHook runs the named hook with args, feeding it stdin. A missing hook
+// does nothing; a hook that is not executable is skipped with a hint. An
+// error is returned when the hook exits non-zero.
+func RunHook(name, stdin string, args ...string) error {
+	path := hookPath(name)
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return nil
+	}
+	if runtime.GOOS != "windows" && info.Mode()&0111 == 0 {
+		fmt.Printf("hint: the '%s' hook was ignored because it is not executable\n", name)
+		return nil
+	}
+
+	root, err := storage.FindRepoRoot()
+	if err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("sh", append([]string{abs}, args...)...)
+	} else {
+		cmd = exec.Command(abs, args...)
+	}
+	cmd.Dir = root
+	cmd.Stdin = bytes.NewBufferString(stdin)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s hook failed: %v", name, err)
+	}
+	return nil
+}
+
+// runPostHook runs a hook whose failure cannot undo anything, so it is only
+// reported.
+func runPostHook(name string, args ...string) {
+	if err := RunHook(name, "", args...); err != nil {
+		fmt.Printf("warning: %v\n", err)
+	}
+}
+
+// RunPostCommitHook runs the post-commit hook.
+func RunPostCommitHook() {
+	runPostHook(HookPostCommit)
+}
+
+// currentHeadCommit returns the commit HEAD points at, or "" when there is
+// none.
+func currentHeadCommit() string {
+	branch, err := storage.GetBranch()
+	if err != nil {
+		return ""
+	}
+	commit, err := storage.GetCurrentCommit(branch)
+	if err != nil {
+		return ""
+	}
+	return commit
+}
@@ -0,0 +1,187 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/airbornharsh/hit/internal/config"
+	"github.com/airbornharsh/hit/internal/go_types"
+)
+
+// Identity is who made a commit or tag, and when.
+type Identity struct {
+	Name  string
+	Email string
+	When  time.Time
+}
+
+// String formats the identity as "Name <email>".
+func (id Identity) String() string {
+	if id.Email == "" {
+		return id.Name
+	}
+	return fmt.Sprintf("%s <%s>", id.Name, id.Email)
+}
+
+// ParseIdentity parses "Name <email>". A bare name has no email.
+func ParseIdentity(s string) (Identity, error) {
+	s = strings.TrimSpace(s)
+	open := strings.Index(s, "<")
+	if open < 0 {
+		if s == "" || strings.Contains(s, ">") {
+			return Identity{}, fmt.Errorf("invalid identity '%s', expected 'Name <email>'", s)
+		}
+		return Identity{Name: s}, nil
+	}
+	name := strings.TrimSpace(s[:open])
+	email, rest, ok := strings.Cut(s[open+1:], ">")
+	if !ok || name == "" || strings.TrimSpace(rest) != "" || strings.ContainsAny(email, "<> ") {
+		return Identity{}, fmt.Errorf("invalid identity '%s', expected 'Name <email>'", s)
+	}
+	return Identity{Name: name, Email: email}, nil
+}
+
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"Mon Jan 2 15:04:05 2006 -0700",
+	time.RFC1123Z,
+}
+
+// ParseDate parses a date given on the command line or in the environment:
+// RFC 3339, "YYYY-MM-DD[ HH:MM:SS[ +ZZZZ]]", the log format, or
+// "@<unix seconds> [+ZZZZ]". Dates without an offset are in local time.
+func ParseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "now" {
+		return time.Now(), nil
+	}
+	if unix, ok := strings.CutPrefix(s, "@"); ok {
+		return parseUnixDate(unix)
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date format: %s", s)
+}
+
+// parseUnixDate parses "<seconds> [+ZZZZ]".
+func parseUnixDate(s string) (time.Time, error) {
+	secs, zone, _ := strings.Cut(s, " ")
+	n, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date format: @%s", s)
+	}
+	t := time.Unix(n, 0)
+	if zone == "" {
+		return t, nil
+	}
+	offset, err := time.Parse("-0700", strings.TrimSpace(zone))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timezone in date: %s", zone)
+	}
+	return t.In(offset.Location()), nil
+}
+
+// identityFor resolves the identity for role ("AUTHOR" or "COMMITTER"):
+// $HIT_<role>_NAME, _EMAIL and _DATE win over user.name and user.email from
+// the config, and $USER is the last resort for the name.
+func identityFor(role string) (Identity, error) {
+	id := Identity{
+		Name:  config.GetString("user.name", ""),
+		Email: config.GetString("user.email", ""),
+		When:  time.Now(),
+	}
+	if name := os.Getenv("HIT_" + role + "_NAME"); name != "" {
+		id.Name = name
+	}
+	if email := os.Getenv("HIT_" + role + "_EMAIL"); email != "" {
+		id.Email = email
+	}
+	if id.Name == "" {
+		id.Name = os.Getenv("USER")
+	}
+	if id.Name == "" {
+		return Identity{}, fmt.Errorf("no identity configured; run 'hit config set --global user.name <name>' and 'hit config set --global user.email <email>', or set HIT_%s_NAME", role)
+	}
+	if date := os.Getenv("HIT_" + role + "_DATE"); date != "" {
+		when, err := ParseDate(date)
+		if err != nil {
+			return Identity{}, fmt.Errorf("HIT_%s_DATE: %v", role, err)
+		}
+		id.When = when
+	}
+	return id, nil
+}
+
+// AuthorIdentity returns the author for new commits.
+func AuthorIdentity() (Identity, error) {
+	return identityFor("AUTHOR")
+}
+
+// CommitterIdentity returns the committer for new commits and the tagger for
+// new tags.
+func CommitterIdentity() (Identity, error) {
+	return identityFor("COMMITTER")
+}
+
+// CommitAuthor returns the author recorded in c.
+func CommitAuthor(c go_types.Commit) Identity {
+	id, err := ParseIdentity(c.Author)
+	if err != nil {
+		id = Identity{Name: c.Author}
+	}
+	id.When = c.Timestamp
+	return id
+}
+
+// OverrideAuthor applies an --author value ("Name <email>") and a --date
+// value to base. Empty values leave the field alone.
+func OverrideAuthor(base Identity, author, date string) (Identity, error) {
+	if author != "" {
+		id, err := ParseIdentity(author)
+		if err != nil {
+			return Identity{}, err
+		}
+		base.Name, base.Email = id.Name, id.Email
+	}
+	if date != "" {
+		when, err := ParseDate(date)
+		if err != nil {
+			return Identity{}, err
+		}
+		base.When = when
+	}
+	return base, nil
+}
+
+// StampCommit records author and the current committer on c. A zero author
+// is the current author.
+func StampCommit(c *go_types.Commit, author Identity) error {
+	if author.Name == "" {
+		var err error
+		if author, err = AuthorIdentity(); err != nil {
+			return err
+		}
+	}
+	if author.When.IsZero() {
+		author.When = time.Now()
+	}
+	committer, err := CommitterIdentity()
+	if err != nil {
+		return err
+	}
+	c.Author = author.String()
+	c.Timestamp = author.When
+	c.Committer = committer.String()
+	c.CommitDate = committer.When
+	return nil
+}
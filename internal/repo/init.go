@@ -21,6 +21,7 @@ func InitRepo() error {
 		".hit",
 		".hit/objects",
 		".hit/refs/heads",
+		".hit/refs/tags",
 		".hit/logs/refs/heads",
 	}
 
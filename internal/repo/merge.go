@@ -2,6 +2,7 @@ package repo
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"maps"
 	"os"
@@ -13,255 +14,118 @@ import (
 	"github.com/airbornharsh/hit/internal/storage"
 )
 
-func MergeBranch(currentBranch string, targetBranch string, remoteName string) error {
-	currentCommit, err := getLocalBranchCommit(currentBranch)
-	if err != nil {
-		return fmt.Errorf("failed to get current branch commit: %v", err)
-	}
-
-	targetCommit, err := getRemoteBranchCommit(remoteName, targetBranch)
-	if err != nil {
-		return fmt.Errorf("failed to get target branch commit: %v", err)
-	}
-
-	if isAlreadyMerged(remoteName, targetBranch, currentCommit) {
-		remoteCommits, err := storage.GetRemoteCommits(remoteName, targetBranch)
-		if err != nil {
-			return fmt.Errorf("failed to get remote commits: %v", err)
-		}
-		err = storage.UpdateHeadCommits(currentBranch, remoteCommits)
-		if err != nil {
-			return fmt.Errorf("failed to update head commits: %v", err)
-		}
+// Merge strategies accepted by MergeOptions.Strategy
+const (
+	MergeStrategyRecursive = "recursive" // three-way merge of both trees
+	MergeStrategyOurs      = "ours"      // record the merge but keep the current tree
+)
 
-		err = storage.UpdateHeadRef(currentBranch, targetCommit)
-		if err != nil {
-			return fmt.Errorf("failed to update head ref: %v", err)
-		}
+// ErrMergeConflicts is returned when a merge stops with conflicts for the
+// user to resolve.
+var ErrMergeConflicts = errors.New("automatic merge failed; fix conflicts and then run 'hit merge --continue'")
 
-		err = storage.UpdateWorkingDirectoryAndIndexFromCommit(targetCommit)
-		if err != nil {
-			return fmt.Errorf("failed to update working directory and index: %v", err)
-		}
+// Sides accepted by MergeOptions.Favor
+const (
+	FavorOurs   = "ours"
+	FavorTheirs = "theirs"
+)
 
-		return nil
-	}
+// MergeOptions controls how MergeBranch and MergeRevision combine histories.
+type MergeOptions struct {
+	ConflictStyle string // ConflictStyleMerge (default) or ConflictStyleDiff3
+	Strategy      string // MergeStrategyRecursive (default) or MergeStrategyOurs
+	Favor         string // settle conflicting hunks in favour of FavorOurs or FavorTheirs
+	NoFF          bool   // create a merge commit even when a fast-forward is possible
+	Squash        bool   // stage the merged result without committing or recording a second parent
+	NoVerify      bool   // skip the pre-merge hook
+}
 
-	commonAncestor, err := findCommonAncestor(remoteName, currentBranch, targetBranch, currentCommit, targetCommit)
+// MergeBranch merges the remote-tracking branch remoteName/targetBranch into
+// currentBranch.
+func MergeBranch(currentBranch string, targetBranch string, remoteName string, opts MergeOptions) error {
+	targetCommit, err := getRemoteBranchCommit(remoteName, targetBranch)
 	if err != nil {
-		return fmt.Errorf("failed to find common ancestor: %v", err)
-	}
-
-	if isFastForwardPossible(currentCommit, commonAncestor) {
-		err = performFastForwardMerge(remoteName, currentBranch, targetCommit)
-		if err != nil {
-			return fmt.Errorf("failed to perform fast-forward merge: %v", err)
-		}
-		return nil
+		return fmt.Errorf("failed to get target branch commit: %v", err)
 	}
+	return mergeCommit(currentBranch, targetCommit, remoteName+"/"+targetBranch, opts)
+}
 
-	_, err = detectThreeWayConflicts(currentCommit, targetCommit, commonAncestor)
+// MergeRevision merges any revision accepted by ResolveRevision into
+// currentBranch.
+func MergeRevision(currentBranch string, rev string, opts MergeOptions) error {
+	targetCommit, err := ResolveRevision(rev)
 	if err != nil {
-		return fmt.Errorf("failed to detect three-way conflicts: %v", err)
+		return err
 	}
-
-	err = performThreeWayMerge(remoteName, currentCommit, targetCommit, currentBranch, targetBranch, commonAncestor)
-	if err != nil {
-		return fmt.Errorf("failed to perform three-way merge: %v", err)
+	label := rev
+	if strings.HasPrefix(targetCommit, rev) {
+		label = shortHash(targetCommit)
 	}
-
-	return nil
+	return mergeCommit(currentBranch, targetCommit, label, opts)
 }
 
-func MergeCommitHash(currentBranch string, targetCommit string) error {
-	currentHeadCommit, err := getLocalBranchCommit(currentBranch)
-	if err != nil {
-		return fmt.Errorf("failed to get current branch commit: %v", err)
+// mergeCommit merges targetCommit into currentBranch. targetLabel names the
+// target in messages and conflict markers.
+func mergeCommit(currentBranch, targetCommit, targetLabel string, opts MergeOptions) error {
+	if IsInMergeState() {
+		return fmt.Errorf("a merge is already in progress; run 'hit merge --continue' or 'hit merge --abort'")
 	}
 
-	targetBranch, err := findLocalBranchContainingCommit(targetCommit)
+	currentCommit, err := getLocalBranchCommit(currentBranch)
 	if err != nil {
-		return fmt.Errorf("failed to locate commit in local branches: %v", err)
+		return fmt.Errorf("failed to get current branch commit: %v", err)
 	}
 
-	commonAncestor, err := findCommonAncestorLocal(currentBranch, targetBranch, currentHeadCommit, targetCommit)
+	commonAncestor, err := findMergeBase(currentCommit, targetCommit)
 	if err != nil {
 		return fmt.Errorf("failed to find common ancestor: %v", err)
 	}
 
-	if isFastForwardPossible(currentHeadCommit, commonAncestor) {
-		if err := performFastForwardLocal(currentBranch, targetBranch, targetCommit); err != nil {
-			return err
-		}
+	if commonAncestor == targetCommit {
+		fmt.Println("Already up to date.")
 		return nil
 	}
 
-	if _, err := detectThreeWayConflicts(currentHeadCommit, targetCommit, commonAncestor); err != nil {
-		return fmt.Errorf("failed to detect three-way conflicts: %v", err)
-	}
-
-	if err := performThreeWayMergeLocal(currentHeadCommit, targetCommit, currentBranch, targetBranch, commonAncestor); err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func findLocalBranchContainingCommit(commitHash string) (string, error) {
-	headsDir := filepath.Join(".hit", "refs", "heads")
-	entries, err := os.ReadDir(headsDir)
-	if err != nil {
-		return "", err
-	}
-	for _, e := range entries {
-		if e.IsDir() {
-			continue
-		}
-		name := e.Name()
-		commits, err := storage.GetHeadCommits(name)
-		if err != nil {
-			continue
-		}
-		for _, c := range commits {
-			if c.Hash == commitHash {
-				return name, nil
-			}
+	if !opts.NoVerify {
+		if err := RunHook(HookPreMerge, "", targetCommit); err != nil {
+			return err
 		}
 	}
-	return "", fmt.Errorf("commit %s not found in local branches", commitHash)
-}
 
-func findCommonAncestorLocal(currentBranch, targetBranch, currentCommit, targetCommit string) (string, error) {
-	ancestors1, err := getAllAncestors("", currentBranch, currentCommit, "heads")
-	if err != nil {
-		return "", err
-	}
-	ancestors2, err := getAllAncestors("", targetBranch, targetCommit, "heads")
-	if err != nil {
-		return "", err
-	}
-	for _, a := range ancestors1 {
-		if slices.Contains(ancestors2, a) {
-			return a, nil
-		}
+	if err := applyMerge(currentBranch, currentCommit, targetCommit, commonAncestor, targetLabel, opts); err != nil {
+		return err
 	}
-	return "", fmt.Errorf("no common ancestor found")
-}
 
-func performFastForwardLocal(currentBranch, targetBranch, targetCommit string) error {
-	refPath := filepath.Join(".hit", "refs", "heads", currentBranch)
-	if err := os.WriteFile(refPath, []byte(targetCommit), 0644); err != nil {
-		return fmt.Errorf("failed to update branch reference: %v", err)
-	}
-	if err := storage.UpdateWorkingDirectoryAndIndexFromCommit(targetCommit); err != nil {
-		return fmt.Errorf("failed to update working directory and index: %v", err)
-	}
-	if err := updateLocalLogWithBranchCommits(currentBranch, targetBranch); err != nil {
-		return fmt.Errorf("failed to update local log: %v", err)
+	squash := "0"
+	if opts.Squash {
+		squash = "1"
 	}
+	runPostHook(HookPostMerge, squash)
 	return nil
 }
 
-func updateLocalLogWithBranchCommits(currentBranch, targetBranch string) error {
-	targetCommits, err := storage.GetHeadCommits(targetBranch)
-	if err != nil {
-		return fmt.Errorf("failed to get target branch commits: %v", err)
-	}
-	localCommits, err := storage.GetHeadCommits(currentBranch)
-	if err != nil {
-		localCommits = []go_types.Commit{}
+// applyMerge fast-forwards currentBranch to targetCommit or merges it in
+// with a merge commit, as opts allow. The ours strategy never fast-forwards,
+// since that would take the target's tree.
+func applyMerge(currentBranch, currentCommit, targetCommit, commonAncestor, targetLabel string, opts MergeOptions) error {
+	fastForward := isFastForwardPossible(currentCommit, commonAncestor) && opts.Strategy != MergeStrategyOurs
+	if fastForward && opts.Squash {
+		return stageSquashedTree(targetCommit, targetLabel, currentBranch)
 	}
-	exists := make(map[string]bool)
-	for _, c := range localCommits {
-		exists[c.Hash] = true
-	}
-	all := append([]go_types.Commit{}, localCommits...)
-	for _, c := range targetCommits {
-		if !exists[c.Hash] {
-			all = append(all, c)
-		}
-	}
-	slices.SortFunc(all, func(a, b go_types.Commit) int {
-		if a.Timestamp.Before(b.Timestamp) {
-			return -1
-		} else if a.Timestamp.After(b.Timestamp) {
-			return 1
-		}
-		return 0
-	})
-	return storage.UpdateHeadCommits(currentBranch, all)
-}
 
-func performThreeWayMergeLocal(currentCommit, targetCommit, currentBranch, targetBranch, commonAncestor string) error {
-	mergedTree, hasConflicts, err := performThreeWayFileMerge("origin", targetBranch, currentCommit, targetCommit, commonAncestor)
-	if err != nil {
-		return fmt.Errorf("failed to perform three-way file merge: %v", err)
-	}
-	if hasConflicts {
+	if fastForward && !opts.NoFF {
+		err := performFastForwardMerge(currentBranch, targetLabel, targetCommit)
+		if err != nil {
+			return fmt.Errorf("failed to perform fast-forward merge: %v", err)
+		}
 		return nil
 	}
-	mergeCommit, err := createMergeCommitWithTree(currentCommit, targetCommit, currentBranch, targetBranch, mergedTree)
-	if err != nil {
-		return fmt.Errorf("failed to create merge commit: %v", err)
-	}
-	if err := UpdateLocalLogFromBranch(currentBranch, targetBranch, mergeCommit, commonAncestor); err != nil {
-		return fmt.Errorf("failed to update local log: %v", err)
-	}
-	refPath := filepath.Join(".hit", "refs", "heads", currentBranch)
-	if err := os.WriteFile(refPath, []byte(mergeCommit.Hash), 0644); err != nil {
-		return fmt.Errorf("failed to update branch reference: %v", err)
-	}
-	if err := storage.UpdateWorkingDirectoryAndIndexFromCommit(mergeCommit.Hash); err != nil {
-		return fmt.Errorf("failed to update working directory: %v", err)
-	}
-	_ = mergedTree
-	return nil
-}
 
-func UpdateLocalLogFromBranch(currentBranch, targetBranch string, commit *go_types.Commit, commonAncestor string) error {
-	logPath := filepath.Join(".hit", "logs", "refs", "heads", currentBranch)
-	var commits []go_types.Commit
-	if data, err := os.ReadFile(logPath); err == nil {
-		_ = json.Unmarshal(data, &commits)
-	}
-	targetCommits, err := storage.GetHeadCommits(targetBranch)
-	if err != nil {
-		targetCommits = []go_types.Commit{}
-	}
-	commonIdx := -1
-	for i, c := range targetCommits {
-		if c.Hash == commonAncestor {
-			commonIdx = i
-			break
-		}
-	}
-	newCommits := []go_types.Commit{}
-	if commonIdx >= 0 && commonIdx+1 < len(targetCommits) {
-		newCommits = targetCommits[commonIdx+1:]
-	}
-	commits = append(commits, *commit)
-	exists := make(map[string]bool)
-	for _, c := range commits {
-		exists[c.Hash] = true
-	}
-	for _, c := range newCommits {
-		if !exists[c.Hash] {
-			commits = append(commits, c)
-		}
-	}
-	slices.SortFunc(commits, func(a, b go_types.Commit) int {
-		if a.Timestamp.Before(b.Timestamp) {
-			return -1
-		} else if a.Timestamp.After(b.Timestamp) {
-			return 1
-		}
-		return 0
-	})
-	data, err := json.Marshal(commits)
-	if err != nil {
-		return err
+	err := performThreeWayMerge(currentBranch, targetLabel, currentCommit, targetCommit, commonAncestor, opts)
+	if err != nil && !errors.Is(err, ErrMergeConflicts) {
+		return fmt.Errorf("failed to perform three-way merge: %v", err)
 	}
-	return os.WriteFile(logPath, data, 0644)
+	return err
 }
 
 func getLocalBranchCommit(branchName string) (string, error) {
@@ -282,173 +146,57 @@ func getRemoteBranchCommit(remoteName string, branchName string) (string, error)
 	return strings.TrimSpace(string(data)), nil
 }
 
-func findCommonAncestor(remoteName, currentBranch, targetBranch, currentCommit, targetCommit string) (string, error) {
-	ancestors1, err := getAllAncestors(remoteName, currentBranch, currentCommit, "heads")
-	if err != nil {
-		return "", err
-	}
-
-	ancestors2, err := getAllAncestors(remoteName, targetBranch, targetCommit, "remotes")
-	if err != nil {
-		return "", err
-	}
-
-	for _, ancestor1 := range ancestors1 {
-		if slices.Contains(ancestors2, ancestor1) {
-			return ancestor1, nil
-		}
-	}
-
-	return "", fmt.Errorf("no common ancestor found")
-}
-
 func isFastForwardPossible(currentCommit, commonAncestor string) bool {
 	return currentCommit == commonAncestor
 }
 
-func getAllAncestors(remoteName, branchName, commitHash, branchType string) ([]string, error) {
-	var ancestors []string
-	visited := make(map[string]bool)
-
-	var traverse func(string) error
-	traverse = func(hash string) error {
-		if visited[hash] || hash == "0000000000000000000000000000000000000000" {
-			return nil
-		}
-		visited[hash] = true
-		ancestors = append(ancestors, hash)
-
-		var commit *go_types.Commit
-		var err error
-		if branchType == "heads" {
-			commit, err = storage.GetCommitObject(branchName, hash)
-			if err != nil {
-				return err
-			}
-		} else {
-			commit, err = storage.GetRemoteCommitObject(remoteName, branchName, hash)
-			if err != nil {
-				return err
-			}
-		}
-
-		if commit.Parent != "" && commit.Parent != "0000000000000000000000000000000000000000" {
-			if err := traverse(commit.Parent); err != nil {
-				return err
-			}
-		}
-
-		if commit.OtherParent != "" && commit.OtherParent != "0000000000000000000000000000000000000000" {
-			if err := traverse(commit.OtherParent); err != nil {
-				return err
-			}
-		}
-
-		return nil
-	}
-
-	return ancestors, traverse(commitHash)
-}
-
-func detectThreeWayConflicts(currentCommit, targetCommit, commonAncestor string) ([]string, error) {
-	currentTree, err := storage.GetCommitTree(currentCommit)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get current commit tree: %v", err)
+// reportUnresolvedConflicts lists the conflicts a merge left for the user,
+// leaving out those rerere already resolved.
+func reportUnresolvedConflicts() {
+	cr, err := LoadConflictResolution()
+	if err != nil || cr == nil {
+		return
 	}
-
-	targetTree, err := storage.GetCommitTree(targetCommit)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get target commit tree: %v", err)
+	var paths []string
+	for _, conflict := range cr.GetUnresolvedConflicts() {
+		paths = append(paths, conflict.FilePath)
 	}
-
-	ancestorTree, err := storage.GetCommitTree(commonAncestor)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get ancestor commit tree: %v", err)
+	slices.Sort(paths)
+	for _, path := range paths {
+		fmt.Printf("  Conflict detected in file: %s\n", path)
 	}
-
-	var conflicts []string
-
-	allFiles := make(map[string]bool)
-	for file := range currentTree.Entries {
-		allFiles[file] = true
-	}
-	for file := range targetTree.Entries {
-		allFiles[file] = true
-	}
-	for file := range ancestorTree.Entries {
-		allFiles[file] = true
-	}
-
-	for file := range allFiles {
-		currentHash := currentTree.Entries[file]
-		targetHash := targetTree.Entries[file]
-		ancestorHash := ancestorTree.Entries[file]
-
-		if hasThreeWayConflict(currentHash, targetHash, ancestorHash) {
-			fmt.Printf("  Conflict detected in file: %s\n", file)
-			conflicts = append(conflicts, file)
-		}
-	}
-
-	return conflicts, nil
-}
-
-func hasThreeWayConflict(current, target, ancestor string) bool {
-	if current == target {
-		return false
-	}
-	if current == ancestor || target == ancestor {
-		return false
-	}
-	return true
 }
 
-func isAlreadyMerged(remoteName, targetBranch, currentCommit string) bool {
-	remoteCommits, err := storage.GetRemoteCommits(remoteName, targetBranch)
-	if err != nil {
-		return false
-	}
-
-	currentCommitExists := false
-	for _, commit := range remoteCommits {
-		if commit.Hash == currentCommit {
-			currentCommitExists = true
-		}
-		if currentCommitExists && commit.OtherParent == currentCommit {
-			return true
-		}
-	}
-
-	return false
-}
-
-func performThreeWayMerge(remoteName, currentCommit, targetCommit, currentBranch, targetBranch, commonAncestor string) error {
+func performThreeWayMerge(currentBranch, targetLabel, currentCommit, targetCommit, commonAncestor string, opts MergeOptions) error {
 	fmt.Printf("Performing three-way merge...\n")
 
-	mergedTree, hasConflicts, err := performThreeWayFileMerge(remoteName, targetBranch, currentCommit, targetCommit, commonAncestor)
+	mergedTree, hasConflicts, err := performThreeWayFileMerge(currentBranch, targetLabel, currentCommit, targetCommit, commonAncestor, opts)
 	if err != nil {
 		return fmt.Errorf("failed to perform three-way file merge: %v", err)
 	}
 
 	if hasConflicts {
-		fmt.Printf("✅ [C]Three-way merge completed successfully\n")
-		return nil
+		reportUnresolvedConflicts()
+		return ErrMergeConflicts
 	}
 
-	mergeCommit, err := createMergeCommitWithTree(currentCommit, targetCommit, currentBranch, targetBranch, mergedTree)
+	if opts.Squash {
+		return stageSquashedMerge(mergedTree, targetLabel, currentBranch)
+	}
+
+	mergeCommit, err := createMergeCommitWithTree(currentCommit, targetCommit, mergeMessage(targetLabel, currentBranch), mergedTree)
 	if err != nil {
 		return fmt.Errorf("failed to create merge commit: %v", err)
 	}
 
-	err = UpdateLocalLog(remoteName, currentBranch, mergeCommit, commonAncestor)
+	err = updateLocalLog(currentBranch, targetCommit, mergeCommit)
 	if err != nil {
 		return fmt.Errorf("failed to update local log: %v", err)
 	}
 
-	refPath := filepath.Join(".hit", "refs", "heads", currentBranch)
-	err = os.WriteFile(refPath, []byte(mergeCommit.Hash), 0644)
+	err = updateBranchRef(currentBranch, mergeCommit.Hash, fmt.Sprintf("merge %s: Merge made by the '%s' strategy.", targetLabel, mergeStrategyName(opts)))
 	if err != nil {
-		return fmt.Errorf("failed to update branch reference: %v", err)
+		return err
 	}
 
 	err = storage.UpdateWorkingDirectoryAndIndexFromCommit(mergeCommit.Hash)
@@ -460,141 +208,74 @@ func performThreeWayMerge(remoteName, currentCommit, targetCommit, currentBranch
 	return nil
 }
 
-func UpdateLocalLog(remoteName, branchName string, commit *go_types.Commit, commonAncestor string) error {
-	logPath := filepath.Join(".hit", "logs", "refs", "heads", branchName)
-	logRemotePath := filepath.Join(".hit", "logs", "refs", "remotes", remoteName, branchName)
-
-	var commits []go_types.Commit
-	logData, err := os.ReadFile(logPath)
+func performFastForwardMerge(currentBranch, targetLabel, targetCommit string) error {
+	err := updateBranchRef(currentBranch, targetCommit, fmt.Sprintf("merge %s: Fast-forward", targetLabel))
 	if err != nil {
-		commits = []go_types.Commit{}
-	} else {
-		if uErr := json.Unmarshal(logData, &commits); uErr != nil {
-			commits = []go_types.Commit{}
-		}
-	}
-
-	var remoteCommits []go_types.Commit
-	logRemoteData, err := os.ReadFile(logRemotePath)
-	if err != nil {
-		remoteCommits = []go_types.Commit{}
-	} else {
-		if uErr := json.Unmarshal(logRemoteData, &remoteCommits); uErr != nil {
-			remoteCommits = []go_types.Commit{}
-		}
-	}
-
-	commonAncestorIndex := -1
-	for i, commit := range remoteCommits {
-		if commit.Hash == commonAncestor {
-			commonAncestorIndex = i
-			break
-		}
-	}
-	newCommits := []go_types.Commit{}
-	if commonAncestorIndex >= 0 && commonAncestorIndex+1 < len(remoteCommits) {
-		newCommits = remoteCommits[commonAncestorIndex+1:]
-	}
-
-	commits = append(commits, *commit)
-
-	commitExists := make(map[string]bool)
-	for _, commit := range commits {
-		commitExists[commit.Hash] = true
-	}
-
-	for _, commit := range remoteCommits {
-		if commitExists[commit.Hash] {
-			continue
-		}
-		commits = append(commits, commit)
-	}
-
-	for _, commit := range newCommits {
-		if commitExists[commit.Hash] {
-			continue
-		}
-		commits = append(commits, commit)
+		return err
 	}
 
-	slices.SortFunc(commits, func(a, b go_types.Commit) int {
-		if a.Timestamp.Before(b.Timestamp) {
-			return -1
-		} else if a.Timestamp.After(b.Timestamp) {
-			return 1
-		}
-		return 0
-	})
-
-	updatedLogData, err := json.Marshal(commits)
+	err = storage.UpdateWorkingDirectoryAndIndexFromCommit(targetCommit)
 	if err != nil {
-		return fmt.Errorf("failed to marshal updated log: %v", err)
-	}
-
-	if mkErr := os.MkdirAll(filepath.Dir(logPath), 0755); mkErr != nil {
-		return fmt.Errorf("failed to ensure log directory: %v", mkErr)
-	}
-	if mkErr := os.MkdirAll(filepath.Dir(logRemotePath), 0755); mkErr != nil {
-		return fmt.Errorf("failed to ensure remote log directory: %v", mkErr)
+		return fmt.Errorf("failed to update working directory and index: %v", err)
 	}
 
-	err = os.WriteFile(logPath, updatedLogData, 0644)
+	err = updateLocalLog(currentBranch, targetCommit)
 	if err != nil {
-		return fmt.Errorf("failed to write updated log: %v", err)
+		return fmt.Errorf("failed to update local log: %v", err)
 	}
 
 	return nil
 }
 
-func performFastForwardMerge(remoteName, currentBranch, targetCommit string) error {
-	refPath := filepath.Join(".hit", "refs", "heads", currentBranch)
-	err := os.WriteFile(refPath, []byte(targetCommit), 0644)
+// missingCommits returns the logged commits reachable from targetCommit
+// that the log of branchName does not contain yet.
+func missingCommits(branchName, targetCommit string) ([]go_types.Commit, error) {
+	graph, err := loadCommitGraph()
 	if err != nil {
-		return fmt.Errorf("failed to update branch reference: %v", err)
+		return nil, fmt.Errorf("failed to load commit graph: %v", err)
 	}
 
-	err = storage.UpdateWorkingDirectoryAndIndexFromCommit(targetCommit)
+	localCommits, err := storage.GetHeadCommits(branchName)
 	if err != nil {
-		return fmt.Errorf("failed to update working directory and index: %v", err)
+		localCommits = []go_types.Commit{}
 	}
-
-	err = updateLocalLogWithRemoteCommits(remoteName, currentBranch)
-	if err != nil {
-		return fmt.Errorf("failed to update local log: %v", err)
+	known := make(map[string]bool)
+	for _, commit := range localCommits {
+		known[commit.Hash] = true
 	}
 
-	return nil
-}
-
-func updateLocalLogWithRemoteCommits(remoteName, branchName string) error {
-	remoteCommits, err := storage.GetRemoteCommits(remoteName, branchName)
-	if err != nil {
-		return fmt.Errorf("failed to get remote commits: %v", err)
+	var missing []go_types.Commit
+	for hash := range reachableCommits(graph, targetCommit) {
+		commit, ok := graph[hash]
+		if ok && !known[hash] {
+			missing = append(missing, commit)
+		}
 	}
+	return missing, nil
+}
 
+// updateLocalLog adds the commits brought in from targetCommit, plus any
+// extra commits such as the merge commit itself, to the log of branchName.
+func updateLocalLog(branchName, targetCommit string, extra ...*go_types.Commit) error {
 	localCommits, err := storage.GetHeadCommits(branchName)
 	if err != nil {
 		localCommits = []go_types.Commit{}
 	}
 
-	commitExists := make(map[string]bool)
-	for _, commit := range localCommits {
-		commitExists[commit.Hash] = true
+	newCommits, err := missingCommits(branchName, targetCommit)
+	if err != nil {
+		return err
 	}
 
-	allCommits := make([]go_types.Commit, 0, len(localCommits)+len(remoteCommits))
-	allCommits = append(allCommits, localCommits...)
-
-	for _, commit := range remoteCommits {
-		if !commitExists[commit.Hash] {
-			allCommits = append(allCommits, commit)
-		}
+	allCommits := append(localCommits, newCommits...)
+	for _, commit := range extra {
+		allCommits = append(allCommits, *commit)
 	}
 
 	slices.SortFunc(allCommits, func(a, b go_types.Commit) int {
-		if a.Timestamp.Before(b.Timestamp) {
+		if a.CommittedAt().Before(b.CommittedAt()) {
 			return -1
-		} else if a.Timestamp.After(b.Timestamp) {
+		} else if a.CommittedAt().After(b.CommittedAt()) {
 			return 1
 		}
 		return 0
@@ -608,7 +289,8 @@ func updateLocalLogWithRemoteCommits(remoteName, branchName string) error {
 	return nil
 }
 
-func performThreeWayFileMerge(remoteName, targetBranch, currentCommit, targetCommit, commonAncestor string) (*go_types.Tree, bool, error) {
+// performThreeWayFileMerge merges the target tree into the current one.
+func performThreeWayFileMerge(currentBranch, targetLabel, currentCommit, targetCommit, commonAncestor string, opts MergeOptions) (*go_types.Tree, bool, error) {
 	currentTree, err := storage.GetCommitTree(currentCommit)
 	if err != nil {
 		return nil, false, fmt.Errorf("failed to get current tree: %v", err)
@@ -624,6 +306,94 @@ func performThreeWayFileMerge(remoteName, targetBranch, currentCommit, targetCom
 		return nil, false, fmt.Errorf("failed to get ancestor tree: %v", err)
 	}
 
+	markers := ConflictMarkers{
+		Current:  currentBranch,
+		Base:     shortHash(commonAncestor),
+		Target:   targetLabel,
+		ShowBase: opts.ConflictStyle == ConflictStyleDiff3,
+	}
+
+	// Initialize conflict resolution with parent and otherParent. A squash
+	// merge records no second parent, so its commit is an ordinary one.
+	var conflictResolution *ConflictResolution
+	if opts.Squash {
+		conflictResolution = CreateConflictResolution()
+		conflictResolution.Message = squashMessage(targetLabel, currentBranch)
+	} else {
+		conflictResolution = CreateMergeConflictResolution(currentCommit, targetCommit, mergeMessage(targetLabel, currentBranch))
+	}
+
+	var result *treeMergeResult
+	if opts.Strategy == MergeStrategyOurs {
+		result = &treeMergeResult{
+			Tree:        &go_types.Tree{Entries: maps.Clone(currentTree.Entries)},
+			NonConflict: map[string]string{},
+		}
+	} else {
+		result, err = mergeTrees(currentTree, targetTree, ancestorTree, markers, opts.Favor, conflictResolution)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	mergedTree := result.Tree
+	hasConflicts := result.HasConflicts
+	nonConflictFiles := result.NonConflict
+	deletedFiles := result.Deleted
+
+	if hasConflicts && opts.Squash {
+		err = updateWorkingDirectoryWithConflictsAndNonConflicts(conflictResolution, nonConflictFiles, deletedFiles)
+		if err != nil {
+			return nil, true, err
+		}
+		return nil, true, nil
+	}
+
+	if hasConflicts {
+		remoteCommits, err := missingCommits(currentBranch, targetCommit)
+		if err != nil {
+			return nil, true, err
+		}
+
+		err = conflictResolution.AddRemoteCommits(remoteCommits)
+		if err != nil {
+			return nil, true, fmt.Errorf("failed to add remote commits: %v", err)
+		}
+
+		err = updateWorkingDirectoryWithConflictsAndNonConflicts(conflictResolution, nonConflictFiles, deletedFiles)
+		if err != nil {
+			return nil, true, err
+		}
+
+		return nil, true, nil
+	}
+
+	treeData, err := json.Marshal(mergedTree)
+	if err != nil {
+		return nil, false, err
+	}
+
+	treeHash := storage.Hash(treeData)
+	err = storage.WriteObject(treeHash, treeData)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return mergedTree, false, nil
+}
+
+// treeMergeResult is the outcome of mergeTrees. NonConflict maps the paths
+// merged cleanly to their new content, Deleted lists paths the merge removes.
+type treeMergeResult struct {
+	Tree         *go_types.Tree
+	NonConflict  map[string]string
+	Deleted      []string
+	HasConflicts bool
+}
+
+// mergeTrees merges targetTree into currentTree against ancestorTree path by
+// path. Conflicts are added to cr; conflicts with a recorded resolution are
+// added already resolved.
+func mergeTrees(currentTree, targetTree, ancestorTree *go_types.Tree, markers ConflictMarkers, favor string, cr *ConflictResolution) (*treeMergeResult, error) {
 	allFiles := make(map[string]bool)
 	for file := range currentTree.Entries {
 		allFiles[file] = true
@@ -639,111 +409,189 @@ func performThreeWayFileMerge(remoteName, targetBranch, currentCommit, targetCom
 		Entries: make(map[string]string),
 		Parent:  "",
 	}
-
-	// Initialize conflict resolution with parent and otherParent
-	conflictResolution := CreateMergeConflictResolution(currentCommit, targetCommit, "Merge branch '"+targetCommit+"' into '"+currentCommit+"'")
 	var hasConflicts bool
 	var nonConflictFiles = make(map[string]string) // For non-conflict files
+	var deletedFiles []string
 
-	for file := range allFiles {
+	for _, file := range slices.Sorted(maps.Keys(allFiles)) {
 		currentHash := currentTree.Entries[file]
 		targetHash := targetTree.Entries[file]
-		ancestorHash := ancestorTree.Entries[file] // Keep for tracking but don't use in merge
+		ancestorHash := ancestorTree.Entries[file]
 
-		mergedHash, mergedContent, err := mergeFileThreeWay(currentHash, targetHash)
+		result, err := mergeFileThreeWay(currentHash, targetHash, ancestorHash, markers, favor)
 		if err != nil {
-			return nil, false, err
+			return nil, err
 		}
 
-		if mergedHash == "" {
+		if result.Conflict != "" {
+			cr.AddConflict(file, result.Conflict, currentHash, targetHash, ancestorHash, result.Content)
+			hasConflicts = true
+
+			if resolved, ok := rerereResolve(currentHash, targetHash, ancestorHash); ok {
+				hash := storage.Hash([]byte(resolved))
+				if err := storage.WriteObject(hash, []byte(resolved)); err != nil {
+					return nil, err
+				}
+				conflict := &cr.Conflicts[len(cr.Conflicts)-1]
+				conflict.Status = "resolved"
+				conflict.Rerere = true
+				cr.Resolved = append(cr.Resolved, file)
+				mergedTree.Entries[file] = hash
+				nonConflictFiles[file] = hash
+				fmt.Printf("Resolved '%s' using previous resolution.\n", file)
+			}
 			continue
 		}
 
-		if HasConflictMarkers(mergedContent) {
-			conflictResolution.AddConflict(file, currentHash, targetHash, ancestorHash, mergedContent)
-			hasConflicts = true
-		} else {
-			mergedTree.Entries[file] = mergedHash
-			nonConflictFiles[file] = mergedHash
+		mergedHash := result.Hash
+		if mergedHash == "" {
+			if currentHash != "" {
+				deletedFiles = append(deletedFiles, file)
+			}
+			continue
 		}
+
+		mergedTree.Entries[file] = mergedHash
+		nonConflictFiles[file] = mergedHash
 	}
 
-	if hasConflicts {
-		var remoteCommits []go_types.Commit
-		allRemoteCommits, err := storage.GetRemoteCommits(remoteName, targetBranch)
-		if err != nil {
-			return nil, true, fmt.Errorf("failed to get remote commits: %v", err)
-		}
+	return &treeMergeResult{
+		Tree:         mergedTree,
+		NonConflict:  nonConflictFiles,
+		Deleted:      deletedFiles,
+		HasConflicts: hasConflicts,
+	}, nil
+}
 
-		for i, commit := range allRemoteCommits {
-			if commit.Parent == commonAncestor {
-				remoteCommits = allRemoteCommits[i:]
-				break
-			}
-		}
+// fileMergeResult is the outcome of merging one path. Conflict holds the
+// conflict kind and is empty for a clean merge; Hash is empty when the file
+// is deleted by the merge or conflicted.
+type fileMergeResult struct {
+	Hash     string
+	Content  string
+	Conflict string
+}
 
-		err = conflictResolution.AddRemoteCommits(remoteCommits)
+// mergeFileThreeWay merges one path of the current and target trees against
+// the common ancestor. An empty hash means the file does not exist on that
+// side.
+func mergeFileThreeWay(currentHash, targetHash, ancestorHash string, markers ConflictMarkers, favor string) (fileMergeResult, error) {
+	var resultHash string
+	switch {
+	case currentHash == targetHash, targetHash == ancestorHash:
+		resultHash = currentHash
+	case currentHash == ancestorHash:
+		resultHash = targetHash
+	case targetHash == "":
+		// Modified here, deleted there: keep our version in the working tree.
+		content, err := storage.GetFileContentFromHash(currentHash)
 		if err != nil {
-			return nil, true, fmt.Errorf("failed to add remote commits: %v", err)
+			return fileMergeResult{}, err
 		}
-
-		err = conflictResolution.SaveConflictResolution()
+		return fileMergeResult{Content: content, Conflict: ConflictKindModifyDelete}, nil
+	case currentHash == "":
+		content, err := storage.GetFileContentFromHash(targetHash)
 		if err != nil {
-			return nil, true, err
+			return fileMergeResult{}, err
 		}
-
-		err = updateWorkingDirectoryWithConflictsAndNonConflicts(conflictResolution, nonConflictFiles)
+		return fileMergeResult{Content: content, Conflict: ConflictKindDeleteModify}, nil
+	default:
+		mergedHash, mergedContent, conflicted, err := PerformAdvancedFileMerge(currentHash, targetHash, ancestorHash, markers, favor)
 		if err != nil {
-			return nil, true, err
+			return fileMergeResult{}, err
 		}
+		if !conflicted {
+			return fileMergeResult{Hash: mergedHash, Content: mergedContent}, nil
+		}
+		kind := ConflictKindContent
+		if ancestorHash == "" {
+			kind = ConflictKindAddAdd
+		}
+		return fileMergeResult{Content: mergedContent, Conflict: kind}, nil
+	}
 
-		return nil, true, nil
+	if resultHash == "" {
+		return fileMergeResult{}, nil
+	}
+	content, err := storage.GetFileContentFromHash(resultHash)
+	if err != nil {
+		return fileMergeResult{}, err
 	}
+	return fileMergeResult{Hash: resultHash, Content: content}, nil
+}
 
-	treeData, err := json.Marshal(mergedTree)
+// stageSquashedTree writes the tree of commitHash into the working directory
+// and index as staged changes, leaving the branch ref untouched.
+func stageSquashedTree(commitHash, targetLabel, currentBranch string) error {
+	tree, err := storage.GetCommitTree(commitHash)
 	if err != nil {
-		return nil, false, err
+		return fmt.Errorf("failed to get target tree: %v", err)
 	}
+	return stageSquashedMerge(tree, targetLabel, currentBranch)
+}
 
-	treeHash := storage.Hash(treeData)
-	err = storage.WriteObject(treeHash, treeData)
+// stageSquashedMerge stages a merged tree for the next ordinary commit and
+// stores a prepared message for it.
+func stageSquashedMerge(tree *go_types.Tree, targetLabel, currentBranch string) error {
+	headHash, err := storage.GetHeadHash()
 	if err != nil {
-		return nil, false, err
+		return err
+	}
+	squashTree := &go_types.Tree{Entries: maps.Clone(tree.Entries), Parent: headHash}
+	treeHash, err := storeTree(squashTree)
+	if err != nil {
+		return fmt.Errorf("failed to store squashed tree: %v", err)
+	}
+	if err := storage.UpdateWorkingDirectoryAndIndexFromCommit(treeHash); err != nil {
+		return fmt.Errorf("failed to update working directory and index: %v", err)
+	}
+	if err := markIndexChanged(); err != nil {
+		return err
 	}
 
-	return mergedTree, false, nil
+	conflictResolution := CreateConflictResolution()
+	conflictResolution.Message = squashMessage(targetLabel, currentBranch)
+	if err := conflictResolution.SaveConflictResolution(); err != nil {
+		return err
+	}
+
+	fmt.Println("Squash merge staged; run 'hit commit' to record it")
+	return nil
 }
 
-func mergeFileThreeWay(currentHash, targetHash string) (string, string, error) {
-	if targetHash == "" {
-		content, err := storage.GetFileContentFromHash(currentHash)
-		if err != nil {
-			return "", "", err
-		}
-		return currentHash, content, nil
+func mergeMessage(targetLabel, currentBranch string) string {
+	if strings.Trim(targetLabel, "0123456789abcdef") == "" {
+		return fmt.Sprintf("Merge commit '%s' into %s", targetLabel, currentBranch)
 	}
-	if currentHash == targetHash {
-		content, err := storage.GetFileContentFromHash(currentHash)
-		if err != nil {
-			return "", "", err
-		}
-		return currentHash, content, nil
-	}
-	mergedHash, mergedContent, _, err := PerformAdvancedFileMerge(currentHash, targetHash)
-	if err != nil {
-		return "", "", err
+	return fmt.Sprintf("Merge branch '%s' into %s", targetLabel, currentBranch)
+}
+
+func mergeStrategyName(opts MergeOptions) string {
+	if opts.Strategy == "" {
+		return MergeStrategyRecursive
 	}
-	return mergedHash, mergedContent, nil
+	return opts.Strategy
 }
 
-func createMergeCommitWithTree(currentCommit, targetCommit, currentBranch, targetBranch string, tree *go_types.Tree) (*go_types.Commit, error) {
-	var message string
-	if targetBranch == currentBranch {
-		message = fmt.Sprintf("Merging Commit %s into %s", targetCommit, currentCommit)
-	} else {
-		message = fmt.Sprintf("Merge branch '%s' into %s", targetBranch, currentBranch)
+func squashMessage(targetLabel, currentBranch string) string {
+	return fmt.Sprintf("Squashed merge of '%s' into %s", targetLabel, currentBranch)
+}
+
+func markIndexChanged() error {
+	return writeIndexEntries(readIndexEntries())
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
 	}
+	return hash
+}
 
+func createMergeCommitWithTree(currentCommit, targetCommit, message string, tree *go_types.Tree) (*go_types.Commit, error) {
+	// Record the first parent in the tree so the merge stays reachable when
+	// walking tree objects.
+	tree.Parent = currentCommit
 	treeData, err := json.Marshal(tree)
 	if err != nil {
 		return nil, err
@@ -760,8 +608,9 @@ func createMergeCommitWithTree(currentCommit, targetCommit, currentBranch, targe
 		Parent:      currentCommit,
 		OtherParent: targetCommit,
 		Message:     message,
-		Author:      os.Getenv("USER"),
-		Timestamp:   go_types.TimeNow(),
+	}
+	if err := StampCommit(&commit, Identity{}); err != nil {
+		return nil, err
 	}
 
 	commitData, err := json.Marshal(commit)
@@ -778,7 +627,11 @@ func createMergeCommitWithTree(currentCommit, targetCommit, currentBranch, targe
 	return &commit, nil
 }
 
-func updateWorkingDirectoryWithConflictsAndNonConflicts(conflictResolution *ConflictResolution, nonConflictFiles map[string]string) error {
+// updateWorkingDirectoryWithConflictsAndNonConflicts saves the merge state,
+// including a snapshot of everything it is about to overwrite so that
+// AbortMerge can undo it, then writes the merge result to the index and the
+// working directory.
+func updateWorkingDirectoryWithConflictsAndNonConflicts(conflictResolution *ConflictResolution, nonConflictFiles map[string]string, deletedFiles []string) error {
 	indexPath := filepath.Join(".hit", "index.json")
 	indexData, err := os.ReadFile(indexPath)
 	if err != nil {
@@ -790,8 +643,26 @@ func updateWorkingDirectoryWithConflictsAndNonConflicts(conflictResolution *Conf
 	if err != nil {
 		return fmt.Errorf("failed to parse index: %v", err)
 	}
+	if index.Entries == nil {
+		index.Entries = make(map[string]string)
+	}
+
+	touched := slices.Collect(maps.Keys(nonConflictFiles))
+	touched = append(touched, deletedFiles...)
+	for _, conflict := range conflictResolution.Conflicts {
+		touched = append(touched, conflict.FilePath)
+	}
+	if err := conflictResolution.recordOriginalState(index, touched); err != nil {
+		return fmt.Errorf("failed to record pre-merge state: %v", err)
+	}
+	if err := conflictResolution.SaveConflictResolution(); err != nil {
+		return err
+	}
 
 	maps.Copy(index.Entries, nonConflictFiles)
+	for _, filePath := range deletedFiles {
+		delete(index.Entries, filePath)
+	}
 	index.Changed = true
 
 	indexData, err = json.MarshalIndent(index, "", "  ")
@@ -814,6 +685,12 @@ func updateWorkingDirectoryWithConflictsAndNonConflicts(conflictResolution *Conf
 		}
 	}
 
+	for _, filePath := range deletedFiles {
+		if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
 	for _, conflict := range conflictResolution.Conflicts {
 		if conflict.Status == "conflict" {
 			err = os.WriteFile(conflict.FilePath, []byte(conflict.Content), 0644)
@@ -0,0 +1,246 @@
+package repo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/airbornharsh/hit/internal/go_types"
+	"github.com/airbornharsh/hit/internal/storage"
+)
+
+// loadCommitGraph collects every commit recorded in the local and
+// remote-tracking branch logs, keyed by hash.
+func loadCommitGraph() (map[string]go_types.Commit, error) {
+	graph := make(map[string]go_types.Commit)
+
+	logDirs := []string{
+		filepath.Join(".hit", "logs", "refs", "heads"),
+		filepath.Join(".hit", "logs", "refs", "remotes"),
+	}
+
+	for _, dir := range logDirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			var commits []go_types.Commit
+			if err := json.Unmarshal(data, &commits); err != nil {
+				return nil
+			}
+			for _, c := range commits {
+				graph[c.Hash] = c
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return graph, nil
+}
+
+// commitParents returns the parents of a commit. Commits missing from the
+// branch logs fall back to the parent recorded in their tree object.
+func commitParents(graph map[string]go_types.Commit, hash string) []string {
+	var parents []string
+	add := func(p string) {
+		if p != "" && p != "0000000000000000000000000000000000000000" && !slices.Contains(parents, p) {
+			parents = append(parents, p)
+		}
+	}
+
+	if c, ok := graph[hash]; ok {
+		add(c.Parent)
+		add(c.OtherParent)
+		return parents
+	}
+
+	tree, err := storage.GetCommitTree(hash)
+	if err == nil {
+		add(tree.Parent)
+	}
+	return parents
+}
+
+// reachableCommits returns every commit reachable from hash, including hash.
+func reachableCommits(graph map[string]go_types.Commit, hash string) map[string]bool {
+	reachable := make(map[string]bool)
+	if hash == "" || hash == "0000000000000000000000000000000000000000" {
+		return reachable
+	}
+
+	queue := []string{hash}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if reachable[current] {
+			continue
+		}
+		reachable[current] = true
+		queue = append(queue, commitParents(graph, current)...)
+	}
+	return reachable
+}
+
+// MergeBases returns the best common ancestors of two commits: the common
+// ancestors that are not themselves ancestors of another common ancestor.
+// Criss-cross histories can have more than one. Results are ordered newest
+// first.
+func MergeBases(commitA, commitB string) ([]string, error) {
+	graph, err := loadCommitGraph()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load commit graph: %v", err)
+	}
+	return mergeBasesInGraph(graph, commitA, commitB), nil
+}
+
+func mergeBasesInGraph(graph map[string]go_types.Commit, commitA, commitB string) []string {
+	ancestorsA := reachableCommits(graph, commitA)
+	if len(ancestorsA) == 0 {
+		return nil
+	}
+
+	// Walk back from B and stop at the first common commit on every path.
+	var candidates []string
+	visited := make(map[string]bool)
+	queue := []string{commitB}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == "" || current == "0000000000000000000000000000000000000000" || visited[current] {
+			continue
+		}
+		visited[current] = true
+		if ancestorsA[current] {
+			candidates = append(candidates, current)
+			continue
+		}
+		queue = append(queue, commitParents(graph, current)...)
+	}
+
+	// Drop candidates that are reachable from another candidate.
+	var bases []string
+	for _, candidate := range candidates {
+		redundant := false
+		for _, other := range candidates {
+			if other == candidate {
+				continue
+			}
+			if reachableCommits(graph, other)[candidate] {
+				redundant = true
+				break
+			}
+		}
+		if !redundant {
+			bases = append(bases, candidate)
+		}
+	}
+
+	slices.SortFunc(bases, func(a, b string) int {
+		ta, tb := graph[a].CommittedAt(), graph[b].CommittedAt()
+		if ta.After(tb) {
+			return -1
+		} else if ta.Before(tb) {
+			return 1
+		}
+		return strings.Compare(a, b)
+	})
+
+	return bases
+}
+
+// findMergeBase returns the ancestor to merge currentCommit and targetCommit
+// against. With several merge bases they are merged into a virtual ancestor
+// tree first, so changes already shared by both sides are not conflicts.
+func findMergeBase(currentCommit, targetCommit string) (string, error) {
+	graph, err := loadCommitGraph()
+	if err != nil {
+		return "", fmt.Errorf("failed to load commit graph: %v", err)
+	}
+
+	bases := mergeBasesInGraph(graph, currentCommit, targetCommit)
+	switch len(bases) {
+	case 0:
+		return "", fmt.Errorf("no common ancestor found")
+	case 1:
+		return bases[0], nil
+	}
+
+	return virtualMergeBase(graph, bases)
+}
+
+// virtualMergeBase merges several merge bases into one tree object and
+// returns its hash. Conflicting regions keep their markers, as the result
+// is only ever used as a merge ancestor.
+func virtualMergeBase(graph map[string]go_types.Commit, bases []string) (string, error) {
+	virtual, err := storage.GetCommitTree(bases[0])
+	if err != nil {
+		return "", fmt.Errorf("failed to get merge base tree: %v", err)
+	}
+
+	for _, next := range bases[1:] {
+		nextTree, err := storage.GetCommitTree(next)
+		if err != nil {
+			return "", fmt.Errorf("failed to get merge base tree: %v", err)
+		}
+
+		ancestorTree := &go_types.Tree{Entries: map[string]string{}}
+		if inner := mergeBasesInGraph(graph, bases[0], next); len(inner) > 0 {
+			innerHash := inner[0]
+			if len(inner) > 1 {
+				if innerHash, err = virtualMergeBase(graph, inner); err != nil {
+					return "", err
+				}
+			}
+			if ancestorTree, err = storage.GetCommitTree(innerHash); err != nil {
+				return "", fmt.Errorf("failed to get merge base tree: %v", err)
+			}
+		}
+
+		allFiles := make(map[string]bool)
+		for _, entries := range []map[string]string{virtual.Entries, nextTree.Entries, ancestorTree.Entries} {
+			for file := range entries {
+				allFiles[file] = true
+			}
+		}
+
+		merged := &go_types.Tree{Entries: make(map[string]string)}
+		for file := range allFiles {
+			result, err := mergeFileThreeWay(virtual.Entries[file], nextTree.Entries[file], ancestorTree.Entries[file], ConflictMarkers{}, "")
+			if err != nil {
+				return "", err
+			}
+			hash := result.Hash
+			if result.Conflict != "" {
+				content := []byte(result.Content)
+				hash = storage.Hash(content)
+				if err := storage.WriteObject(hash, content); err != nil {
+					return "", err
+				}
+			}
+			if hash != "" {
+				merged.Entries[file] = hash
+			}
+		}
+		virtual = merged
+	}
+
+	return storeTree(virtual)
+}
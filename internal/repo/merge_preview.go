@@ -0,0 +1,165 @@
+package repo
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/airbornharsh/hit/internal/storage"
+)
+
+// Outcomes reported for each path by PreviewMerge
+const (
+	MergeOutcomeClean      = "clean"       // changed on one side only, taken as-is
+	MergeOutcomeAutoMerged = "auto-merged" // changed on both sides, merged without conflicts
+	MergeOutcomeConflict   = "conflict"
+)
+
+// MergePreviewFile is the predicted outcome of merging one path. Kind holds
+// the conflict kind for conflicting paths.
+type MergePreviewFile struct {
+	Path    string
+	Outcome string
+	Kind    string
+}
+
+// MergePreview describes what merging a revision would do.
+type MergePreview struct {
+	Target      string
+	Base        string
+	UpToDate    bool
+	FastForward bool
+	Files       []MergePreviewFile
+}
+
+// HasConflicts reports whether the merge would stop with conflicts.
+func (p *MergePreview) HasConflicts() bool {
+	for _, f := range p.Files {
+		if f.Outcome == MergeOutcomeConflict {
+			return true
+		}
+	}
+	return false
+}
+
+// PreviewMerge runs the merge of rev into currentBranch in memory and
+// reports the outcome of every path it would touch. The working tree, index
+// and refs are left alone.
+func PreviewMerge(currentBranch, rev string, opts MergeOptions) (*MergePreview, error) {
+	targetCommit, err := ResolveRevision(rev)
+	if err != nil {
+		return nil, err
+	}
+	currentCommit, err := getLocalBranchCommit(currentBranch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current branch commit: %v", err)
+	}
+	commonAncestor, err := findMergeBase(currentCommit, targetCommit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find common ancestor: %v", err)
+	}
+
+	preview := &MergePreview{Target: targetCommit, Base: commonAncestor}
+	if commonAncestor == targetCommit {
+		preview.UpToDate = true
+		return preview, nil
+	}
+	if isFastForwardPossible(currentCommit, commonAncestor) && !opts.NoFF && !opts.Squash && opts.Strategy != MergeStrategyOurs {
+		preview.FastForward = true
+	}
+
+	preview.Files, err = previewTrees(currentCommit, targetCommit, commonAncestor, opts)
+	if err != nil {
+		return nil, err
+	}
+	return preview, nil
+}
+
+// previewTrees predicts the outcome of merging every path of the two trees.
+// Paths the merge leaves as they are in the current tree are not listed.
+func previewTrees(currentCommit, targetCommit, commonAncestor string, opts MergeOptions) ([]MergePreviewFile, error) {
+	if opts.Strategy == MergeStrategyOurs {
+		return nil, nil
+	}
+
+	currentTree, err := storage.GetCommitTree(currentCommit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current tree: %v", err)
+	}
+	targetTree, err := storage.GetCommitTree(targetCommit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get target tree: %v", err)
+	}
+	ancestorTree, err := storage.GetCommitTree(commonAncestor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ancestor tree: %v", err)
+	}
+
+	allFiles := make(map[string]bool)
+	for _, entries := range []map[string]string{currentTree.Entries, targetTree.Entries, ancestorTree.Entries} {
+		for file := range entries {
+			allFiles[file] = true
+		}
+	}
+
+	var files []MergePreviewFile
+	for file := range allFiles {
+		currentHash := currentTree.Entries[file]
+		targetHash := targetTree.Entries[file]
+		ancestorHash := ancestorTree.Entries[file]
+
+		var outcome, kind string
+		switch {
+		case currentHash == targetHash, targetHash == ancestorHash:
+			continue
+		case currentHash == ancestorHash:
+			outcome = MergeOutcomeClean
+		case targetHash == "":
+			outcome, kind = MergeOutcomeConflict, ConflictKindModifyDelete
+		case currentHash == "":
+			outcome, kind = MergeOutcomeConflict, ConflictKindDeleteModify
+		default:
+			conflicted, err := previewFileMerge(currentHash, targetHash, ancestorHash, opts.Favor)
+			if err != nil {
+				return nil, err
+			}
+			outcome = MergeOutcomeAutoMerged
+			if conflicted {
+				outcome, kind = MergeOutcomeConflict, ConflictKindContent
+				if ancestorHash == "" {
+					kind = ConflictKindAddAdd
+				}
+			}
+		}
+		files = append(files, MergePreviewFile{Path: file, Outcome: outcome, Kind: kind})
+	}
+
+	slices.SortFunc(files, func(a, b MergePreviewFile) int {
+		if a.Path < b.Path {
+			return -1
+		} else if a.Path > b.Path {
+			return 1
+		}
+		return 0
+	})
+	return files, nil
+}
+
+// previewFileMerge reports whether merging the two versions of a file would
+// conflict, without storing anything.
+func previewFileMerge(currentHash, targetHash, ancestorHash, favor string) (bool, error) {
+	current, err := storage.GetFileContentFromHash(currentHash)
+	if err != nil {
+		return false, err
+	}
+	target, err := storage.GetFileContentFromHash(targetHash)
+	if err != nil {
+		return false, err
+	}
+	ancestor, err := storage.GetFileContentFromHash(ancestorHash)
+	if err != nil {
+		return false, err
+	}
+
+	_, conflicted, err := PerformThreeWayLineMerge(ancestor, current, target, ConflictMarkers{}, favor)
+	return conflicted, err
+}
@@ -0,0 +1,70 @@
+package repo
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+
+	"github.com/airbornharsh/hit/internal/go_types"
+	"github.com/airbornharsh/hit/internal/storage"
+)
+
+// recordOriginalState remembers the index and the current contents of the
+// given working tree paths before a merge overwrites them.
+func (cr *ConflictResolution) recordOriginalState(index go_types.Index, paths []string) error {
+	cr.OrigIndex = &go_types.Index{Entries: maps.Clone(index.Entries), Changed: index.Changed}
+	cr.OrigFiles = make(map[string]string)
+
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			cr.OrigFiles[path] = ""
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		hash := storage.Hash(content)
+		if err := storage.WriteObject(hash, content); err != nil {
+			return err
+		}
+		cr.OrigFiles[path] = hash
+	}
+
+	return nil
+}
+
+// AbortMerge puts the working tree and index back the way they were before
+// the merge in progress started and clears the merge state.
+func AbortMerge() error {
+	cr, err := LoadConflictResolution()
+	if err != nil {
+		return fmt.Errorf("failed to load merge state: %v", err)
+	}
+	if cr == nil || cr.OrigIndex == nil {
+		return fmt.Errorf("there is no merge to abort")
+	}
+
+	for path, hash := range cr.OrigFiles {
+		if hash == "" {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove %s: %v", path, err)
+			}
+			continue
+		}
+		if err := storage.RestoreFileFromObject(path, hash); err != nil {
+			return err
+		}
+	}
+
+	indexData, err := storage.MarshalIndent(cr.OrigIndex, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal index: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(".hit", "index.json"), indexData, 0644); err != nil {
+		return fmt.Errorf("failed to restore index: %v", err)
+	}
+
+	return ClearConflictResolution()
+}
@@ -0,0 +1,144 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/airbornharsh/hit/internal/storage"
+)
+
+// RunMergeTool resolves unresolved conflicts with an external tool. The tool
+// command runs through the shell with BASE, LOCAL, REMOTE and MERGED set in
+// its environment, e.g. `meld "$LOCAL" "$MERGED" "$REMOTE"`. A conflict is
+// staged, and so marked resolved, when the tool exits successfully and the
+// merged file no longer contains conflict markers. An empty paths list
+// processes every unresolved conflict.
+func RunMergeTool(toolCmd string, paths []string) error {
+	cr, err := LoadConflictResolution()
+	if err != nil {
+		return fmt.Errorf("failed to load conflict resolution: %v", err)
+	}
+	if cr == nil || !cr.HasUnresolvedConflicts() {
+		fmt.Println("No files need merging")
+		return nil
+	}
+
+	conflicts, err := conflictsMatching(cr.GetUnresolvedConflicts(), paths)
+	if err != nil {
+		return err
+	}
+
+	tempDir, err := os.MkdirTemp("", "hit-mergetool-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for _, conflict := range conflicts {
+		fmt.Printf("Merging %s (%s)\n", conflict.FilePath, conflict.Kind)
+		if err := runMergeToolOnFile(toolCmd, tempDir, conflict); err != nil {
+			fmt.Printf("  %v\n", err)
+			continue
+		}
+		fmt.Printf("  resolved %s\n", conflict.FilePath)
+	}
+
+	return nil
+}
+
+// conflictsMatching returns the conflicts at or below paths, which are given
+// as on the command line. Every path must match a conflict. No paths match
+// every conflict.
+func conflictsMatching(conflicts []ConflictFile, paths []string) ([]ConflictFile, error) {
+	if len(paths) == 0 {
+		return conflicts, nil
+	}
+	var matched []ConflictFile
+	var unmatched []string
+	selected := make(map[string]bool)
+	for _, p := range paths {
+		rel, err := repoPath(p)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, conflict := range conflicts {
+			if rel == "." || conflict.FilePath == rel || strings.HasPrefix(conflict.FilePath, rel+"/") {
+				found = true
+				if !selected[conflict.FilePath] {
+					selected[conflict.FilePath] = true
+					matched = append(matched, conflict)
+				}
+			}
+		}
+		if !found {
+			unmatched = append(unmatched, p)
+		}
+	}
+	if len(unmatched) > 0 {
+		return nil, fmt.Errorf("pathspec did not match any unresolved conflict: %s", strings.Join(unmatched, ", "))
+	}
+	return matched, nil
+}
+
+// runMergeToolOnFile writes the three sides of one conflict to temp files,
+// runs the tool and stages the result.
+func runMergeToolOnFile(toolCmd, tempDir string, conflict ConflictFile) error {
+	name := filepath.Base(conflict.FilePath)
+	sides := []struct {
+		env  string
+		hash string
+	}{
+		{"BASE", conflict.AncestorHash},
+		{"LOCAL", conflict.CurrentHash},
+		{"REMOTE", conflict.TargetHash},
+	}
+
+	env := os.Environ()
+	for _, side := range sides {
+		content, err := storage.GetFileContentFromHash(side.hash)
+		if err != nil {
+			return fmt.Errorf("failed to read %s version: %v", side.env, err)
+		}
+		sidePath := filepath.Join(tempDir, side.env+"_"+name)
+		if err := os.WriteFile(sidePath, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s version: %v", side.env, err)
+		}
+		env = append(env, side.env+"="+sidePath)
+	}
+	env = append(env, "MERGED="+conflict.FilePath)
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", toolCmd)
+	} else {
+		cmd = exec.Command("sh", "-c", toolCmd)
+	}
+	cmd.Env = env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("merge tool failed, leaving %s unresolved: %v", conflict.FilePath, err)
+	}
+
+	if _, err := os.Stat(conflict.FilePath); os.IsNotExist(err) {
+		// The tool removed the file: stage the deletion.
+		entries := readIndexEntries()
+		delete(entries, conflict.FilePath)
+		if err := writeIndexEntries(entries); err != nil {
+			return fmt.Errorf("leaving %s unresolved: %v", conflict.FilePath, err)
+		}
+		markConflictResolved(conflict.FilePath, nil)
+		return nil
+	}
+
+	if _, err := AddFile(conflict.FilePath); err != nil {
+		return fmt.Errorf("leaving %s unresolved: %v", conflict.FilePath, err)
+	}
+	return nil
+}
@@ -0,0 +1,610 @@
+package repo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/airbornharsh/hit/internal/config"
+	"github.com/airbornharsh/hit/internal/storage"
+	"github.com/airbornharsh/hit/utils"
+)
+
+// patchContext is the number of unchanged lines shown around each hunk.
+const patchContext = 3
+
+// patchHunk is one hunk offered for selection: a run of changes against the
+// base file, shown with the base lines [start, end) around them.
+type patchHunk struct {
+	changes  []lineHunk
+	start    int
+	end      int
+	accepted bool
+}
+
+// patchFile is a file whose changes are picked hunk by hunk. Accepted
+// hunks are applied to base, turning it towards other. With reverse set,
+// hunks are shown as the change from other to base, as when unstaging.
+type patchFile struct {
+	path    string
+	base    []string
+	reverse bool
+	hunks   []*patchHunk
+}
+
+// patchMode describes what hit add -p, reset -p and revert -p do with the
+// hunks the user accepts.
+type patchMode struct {
+	verb   string // "Stage", "Unstage" or "Discard"
+	suffix string // appended to the prompt
+}
+
+var (
+	patchModeAdd    = patchMode{verb: "Stage"}
+	patchModeReset  = patchMode{verb: "Unstage"}
+	patchModeRevert = patchMode{verb: "Discard", suffix: " from worktree"}
+)
+
+const patchHelp = `y - %[1]s this hunk
+n - do not %[1]s this hunk
+s - split the current hunk into smaller hunks
+e - manually edit the current hunk
+q - quit; do not %[1]s this hunk or any of the remaining ones
+? - print help
+`
+
+// errPatchQuit stops the selection after the user answers q.
+var errPatchQuit = fmt.Errorf("quit")
+
+// patchSession reads answers from the user across files.
+type patchSession struct {
+	mode patchMode
+	in   *bufio.Reader
+}
+
+func newPatchSession(mode patchMode) *patchSession {
+	return &patchSession{mode: mode, in: bufio.NewReader(os.Stdin)}
+}
+
+// newPatchFile splits the change from base to other into hunks. Changes
+// whose context would overlap are kept in one hunk, which s splits again.
+func newPatchFile(path string, base, other []string, reverse bool) *patchFile {
+	f := &patchFile{path: path, base: base, reverse: reverse}
+	var current *patchHunk
+	for _, change := range diffLineHunks(base, other) {
+		if current != nil && change.BaseStart-current.changes[len(current.changes)-1].BaseEnd <= 2*patchContext {
+			current.changes = append(current.changes, change)
+			continue
+		}
+		current = &patchHunk{}
+		current.changes = []lineHunk{change}
+		f.hunks = append(f.hunks, current)
+	}
+	for _, h := range f.hunks {
+		f.setRange(h)
+	}
+	return f
+}
+
+// setRange sets the lines shown around h's changes.
+func (f *patchFile) setRange(h *patchHunk) {
+	h.start = max(0, h.changes[0].BaseStart-patchContext)
+	h.end = min(len(f.base), h.changes[len(h.changes)-1].BaseEnd+patchContext)
+}
+
+// split breaks h into one hunk per change.
+func (f *patchFile) split(h *patchHunk) []*patchHunk {
+	var parts []*patchHunk
+	for _, change := range h.changes {
+		part := &patchHunk{changes: []lineHunk{change}}
+		f.setRange(part)
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// offset returns how far the lines of other are shifted from base by the
+// changes before h.
+func (f *patchFile) offset(h *patchHunk) int {
+	offset := 0
+	for _, other := range f.hunks {
+		for _, change := range other.changes {
+			if change.BaseStart < h.changes[0].BaseStart {
+				offset += len(change.Lines) - (change.BaseEnd - change.BaseStart)
+			}
+		}
+	}
+	return offset
+}
+
+// sides returns the lines removed and added by change as the user sees
+// them.
+func (f *patchFile) sides(change lineHunk) (minus, plus []string) {
+	if f.reverse {
+		return change.Lines, f.base[change.BaseStart:change.BaseEnd]
+	}
+	return f.base[change.BaseStart:change.BaseEnd], change.Lines
+}
+
+// lines renders h as the lines of a unified diff, header first.
+func (f *patchFile) lines(h *patchHunk) []string {
+	var body []string
+	minusCount, plusCount := 0, 0
+	pos := h.start
+	for _, change := range h.changes {
+		for _, line := range f.base[pos:change.BaseStart] {
+			body = append(body, " "+line)
+		}
+		minusCount += change.BaseStart - pos
+		plusCount += change.BaseStart - pos
+		minus, plus := f.sides(change)
+		for _, line := range minus {
+			body = append(body, "-"+line)
+		}
+		for _, line := range plus {
+			body = append(body, "+"+line)
+		}
+		minusCount += len(minus)
+		plusCount += len(plus)
+		pos = change.BaseEnd
+	}
+	for _, line := range f.base[pos:h.end] {
+		body = append(body, " "+line)
+	}
+	minusCount += h.end - pos
+	plusCount += h.end - pos
+
+	baseStart, otherStart := h.start+1, h.start+1+f.offset(h)
+	minusStart, plusStart := baseStart, otherStart
+	if f.reverse {
+		minusStart, plusStart = otherStart, baseStart
+	}
+	header := fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", minusStart, minusCount, plusStart, plusCount)
+	return append([]string{header}, body...)
+}
+
+// show prints h, coloured unless color.ui is false.
+func (f *patchFile) show(h *patchHunk) {
+	color := config.GetBool("color.ui", true)
+	for _, line := range f.lines(h) {
+		if !strings.HasSuffix(line, "\n") {
+			line += "\n\\ No newline at end of file\n"
+		}
+		if color {
+			switch line[0] {
+			case '@':
+				line = "\x1b[36m" + strings.TrimSuffix(line, "\n") + "\x1b[0m\n"
+			case '-':
+				line = "\x1b[31m" + strings.TrimSuffix(line, "\n") + "\x1b[0m\n"
+			case '+':
+				line = "\x1b[32m" + strings.TrimSuffix(line, "\n") + "\x1b[0m\n"
+			}
+		}
+		fmt.Print(line)
+	}
+}
+
+// edit lets the user rewrite h in the editor and returns the change it
+// describes, or false when the edit leaves nothing to apply.
+func (f *patchFile) edit(h *patchHunk) (*patchHunk, bool, error) {
+	var b strings.Builder
+	for _, line := range f.lines(h) {
+		b.WriteString(line)
+		if !strings.HasSuffix(line, "\n") {
+			b.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+	b.WriteString("# ---\n" +
+		"# To remove '-' lines, make them ' ' lines (context).\n" +
+		"# To remove '+' lines, delete them.\n" +
+		"# Lines starting with # will be removed.\n" +
+		"# If the patch applies cleanly, the edited hunk will immediately be\n" +
+		"# marked for selection.\n")
+
+	path := filepath.Join(".hit", "addp-hunk-edit.diff")
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return nil, false, fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	defer os.Remove(path)
+	if err := utils.LaunchEditor(path); err != nil {
+		return nil, false, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var minus, plus []string
+	var last byte
+	for _, line := range splitLines(string(data)) {
+		if line == "\n" {
+			line = " \n"
+		}
+		switch line[0] {
+		case '#':
+		case '@':
+		case '\\':
+			// The line before has no newline.
+			if last != '+' && len(minus) > 0 {
+				minus[len(minus)-1] = strings.TrimSuffix(minus[len(minus)-1], "\n")
+			}
+			if last != '-' && len(plus) > 0 {
+				plus[len(plus)-1] = strings.TrimSuffix(plus[len(plus)-1], "\n")
+			}
+		case ' ':
+			minus = append(minus, line[1:])
+			plus = append(plus, line[1:])
+		case '-':
+			minus = append(minus, line[1:])
+		case '+':
+			plus = append(plus, line[1:])
+		default:
+			return nil, false, fmt.Errorf("your edited hunk has a line not starting with ' ', '-' or '+': %s", strings.TrimSuffix(line, "\n"))
+		}
+		last = line[0]
+	}
+
+	from, to := minus, plus
+	if f.reverse {
+		from, to = plus, minus
+	}
+	if !slices.Equal(from, f.base[h.start:h.end]) {
+		return nil, false, fmt.Errorf("your edited hunk does not apply")
+	}
+
+	// Keep only the lines that differ, so the change does not reach into
+	// its neighbours' context.
+	change := lineHunk{BaseStart: h.start, BaseEnd: h.end, Lines: to}
+	for change.BaseStart < change.BaseEnd && len(change.Lines) > 0 && f.base[change.BaseStart] == change.Lines[0] {
+		change.BaseStart++
+		change.Lines = change.Lines[1:]
+	}
+	for change.BaseEnd > change.BaseStart && len(change.Lines) > 0 && f.base[change.BaseEnd-1] == change.Lines[len(change.Lines)-1] {
+		change.BaseEnd--
+		change.Lines = change.Lines[:len(change.Lines)-1]
+	}
+	if change.BaseStart == change.BaseEnd && len(change.Lines) == 0 {
+		return nil, false, nil
+	}
+	edited := &patchHunk{changes: []lineHunk{change}, accepted: true}
+	f.setRange(edited)
+	return edited, true, nil
+}
+
+// result applies the accepted hunks to base.
+func (f *patchFile) result() ([]string, error) {
+	var changes []lineHunk
+	for _, h := range f.hunks {
+		if h.accepted {
+			changes = append(changes, h.changes...)
+		}
+	}
+	slices.SortFunc(changes, func(a, b lineHunk) int { return a.BaseStart - b.BaseStart })
+	for i := 1; i < len(changes); i++ {
+		if changes[i].BaseStart < changes[i-1].BaseEnd {
+			return nil, fmt.Errorf("the selected hunks of %s overlap", f.path)
+		}
+	}
+	return applyHunks(f.base, changes, 0, len(f.base)), nil
+}
+
+// ask prints prompt and returns the user's answer, or "q" at end of input.
+func (s *patchSession) ask(prompt string) string {
+	fmt.Print(prompt)
+	answer, err := s.in.ReadString('\n')
+	if err == io.EOF && answer == "" {
+		fmt.Println()
+		return "q"
+	}
+	return strings.ToLower(strings.TrimSpace(answer))
+}
+
+// selectHunks asks about each hunk of f in turn. It reports whether any
+// hunk was accepted; errPatchQuit means the user quit after f.
+func (s *patchSession) selectHunks(f *patchFile) (bool, error) {
+	fmt.Printf("diff --hit a/%s b/%s\n", f.path, f.path)
+	var quit bool
+	for i := 0; i < len(f.hunks) && !quit; {
+		h := f.hunks[i]
+		f.show(h)
+		options := "y,n,q,e"
+		if len(h.changes) > 1 {
+			options = "y,n,q,s,e"
+		}
+		answer := s.ask(fmt.Sprintf("(%d/%d) %s this hunk%s [%s,?]? ", i+1, len(f.hunks), s.mode.verb, s.mode.suffix, options))
+		switch answer {
+		case "y":
+			h.accepted = true
+			i++
+		case "n":
+			i++
+		case "q":
+			quit = true
+		case "s":
+			if len(h.changes) < 2 {
+				fmt.Println("Sorry, cannot split this hunk")
+				continue
+			}
+			parts := f.split(h)
+			fmt.Printf("Split into %d hunks.\n", len(parts))
+			f.hunks = slices.Insert(slices.Delete(f.hunks, i, i+1), i, parts...)
+		case "e":
+			edited, ok, err := f.edit(h)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			if ok {
+				f.hunks[i] = edited
+			}
+			i++
+		default:
+			fmt.Printf(patchHelp, strings.ToLower(s.mode.verb))
+		}
+	}
+
+	accepted := slices.ContainsFunc(f.hunks, func(h *patchHunk) bool { return h.accepted })
+	if quit {
+		return accepted, errPatchQuit
+	}
+	return accepted, nil
+}
+
+// askWhole asks about a change that cannot be split, such as a deletion.
+func (s *patchSession) askWhole(path, what string) (bool, error) {
+	fmt.Printf("diff --hit a/%s b/%s\n", path, path)
+	for {
+		answer := s.ask(fmt.Sprintf("%s %s%s [y,n,q,?]? ", s.mode.verb, what, s.mode.suffix))
+		switch answer {
+		case "y":
+			return true, nil
+		case "n":
+			return false, nil
+		case "q":
+			return false, errPatchQuit
+		default:
+			fmt.Printf("y - %[1]s this %[2]s\nn - do not %[1]s this %[2]s\nq - quit\n", strings.ToLower(s.mode.verb), what)
+		}
+	}
+}
+
+// patchPaths turns the paths given on the command line into a filter on
+// repository paths. No paths selects everything.
+func patchPaths(paths []string) (func(string) bool, error) {
+	var prefixes []string
+	for _, p := range paths {
+		rel, err := repoPath(p)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, rel)
+	}
+	return func(path string) bool {
+		if len(prefixes) == 0 {
+			return true
+		}
+		for _, prefix := range prefixes {
+			if prefix == "." || path == prefix || strings.HasPrefix(path, prefix+"/") {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// objectLines returns the lines of the object hash, or none for "".
+func objectLines(hash string) ([]string, error) {
+	if hash == "" {
+		return []string{}, nil
+	}
+	content, err := storage.LoadObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load object %s: %v", hash, err)
+	}
+	return splitLines(content), nil
+}
+
+// stageBlob stores content and points the index entry for path at it.
+func stageBlob(entries map[string]string, path string, content []byte) error {
+	hash := storage.Hash(content)
+	if err := storage.WriteObject(hash, content); err != nil {
+		return err
+	}
+	entries[path] = hash
+	return nil
+}
+
+// AddPatch lets the user pick the hunks of the working tree changes to
+// tracked files under paths to stage. Each file gets a new blob built from
+// its index version with the accepted hunks applied.
+func AddPatch(paths []string) error {
+	match, err := patchPaths(paths)
+	if err != nil {
+		return err
+	}
+	entries := readIndexEntries()
+	s := newPatchSession(patchModeAdd)
+	changed := false
+	for _, path := range slices.Sorted(maps.Keys(entries)) {
+		if !match(path) {
+			continue
+		}
+		if CheckFileForConflicts(path) {
+			fmt.Printf("Skipping %s: it has unresolved merge conflicts\n", path)
+			continue
+		}
+		content, readErr := os.ReadFile(path)
+		if os.IsNotExist(readErr) {
+			var ok bool
+			ok, err = s.askWhole(path, "deletion")
+			if ok {
+				delete(entries, path)
+				changed = true
+			}
+		} else if readErr != nil {
+			return readErr
+		} else {
+			if storage.Hash(content) == entries[path] {
+				continue
+			}
+			base, loadErr := objectLines(entries[path])
+			if loadErr != nil {
+				return loadErr
+			}
+			f := newPatchFile(path, base, splitLines(string(content)), false)
+			if len(f.hunks) == 0 {
+				continue
+			}
+			var ok bool
+			ok, err = s.selectHunks(f)
+			if ok {
+				lines, resultErr := f.result()
+				if resultErr != nil {
+					return resultErr
+				}
+				if stageErr := stageBlob(entries, path, []byte(strings.Join(lines, ""))); stageErr != nil {
+					return stageErr
+				}
+				changed = true
+			}
+		}
+		if err == errPatchQuit {
+			break
+		}
+	}
+	if !changed {
+		fmt.Println("No changes staged.")
+		return nil
+	}
+	return writeIndexEntries(entries)
+}
+
+// ResetPatch lets the user pick the hunks of the staged changes under paths
+// to unstage, moving the index versions back towards HEAD.
+func ResetPatch(paths []string) error {
+	match, err := patchPaths(paths)
+	if err != nil {
+		return err
+	}
+	head := map[string]string{}
+	if tree, treeErr := storage.GetHeadTree(); treeErr == nil {
+		head = tree.Entries
+	}
+	entries := readIndexEntries()
+	all := slices.Collect(maps.Keys(entries))
+	all = append(all, slices.Collect(maps.Keys(head))...)
+	slices.Sort(all)
+
+	s := newPatchSession(patchModeReset)
+	changed := false
+	for _, path := range slices.Compact(all) {
+		if !match(path) || entries[path] == head[path] {
+			continue
+		}
+		var ok bool
+		switch {
+		case entries[path] == "":
+			if ok, err = s.askWhole(path, "deletion"); ok {
+				entries[path] = head[path]
+			}
+		case head[path] == "":
+			if ok, err = s.askWhole(path, "addition"); ok {
+				delete(entries, path)
+			}
+		default:
+			base, loadErr := objectLines(entries[path])
+			if loadErr != nil {
+				return loadErr
+			}
+			other, loadErr := objectLines(head[path])
+			if loadErr != nil {
+				return loadErr
+			}
+			f := newPatchFile(path, base, other, true)
+			if len(f.hunks) == 0 {
+				continue
+			}
+			if ok, err = s.selectHunks(f); ok {
+				lines, resultErr := f.result()
+				if resultErr != nil {
+					return resultErr
+				}
+				if stageErr := stageBlob(entries, path, []byte(strings.Join(lines, ""))); stageErr != nil {
+					return stageErr
+				}
+			}
+		}
+		changed = changed || ok
+		if err == errPatchQuit {
+			break
+		}
+	}
+	if !changed {
+		fmt.Println("No changes unstaged.")
+		return nil
+	}
+	return writeIndexEntries(entries)
+}
+
+// RevertPatch lets the user pick the hunks of the working tree changes to
+// tracked files under paths to discard, moving the files back towards the
+// index.
+func RevertPatch(paths []string) error {
+	match, err := patchPaths(paths)
+	if err != nil {
+		return err
+	}
+	entries := readIndexEntries()
+	s := newPatchSession(patchModeRevert)
+	for _, path := range slices.Sorted(maps.Keys(entries)) {
+		if !match(path) {
+			continue
+		}
+		if CheckFileForConflicts(path) {
+			fmt.Printf("Skipping %s: it has unresolved merge conflicts\n", path)
+			continue
+		}
+		content, readErr := os.ReadFile(path)
+		if os.IsNotExist(readErr) {
+			var ok bool
+			if ok, err = s.askWhole(path, "deletion"); ok {
+				if restoreErr := storage.RestoreFileFromObject(path, entries[path]); restoreErr != nil {
+					return restoreErr
+				}
+			}
+		} else if readErr != nil {
+			return readErr
+		} else {
+			if storage.Hash(content) == entries[path] {
+				continue
+			}
+			other, loadErr := objectLines(entries[path])
+			if loadErr != nil {
+				return loadErr
+			}
+			f := newPatchFile(path, splitLines(string(content)), other, true)
+			if len(f.hunks) == 0 {
+				continue
+			}
+			var ok bool
+			if ok, err = s.selectHunks(f); ok {
+				lines, resultErr := f.result()
+				if resultErr != nil {
+					return resultErr
+				}
+				if writeErr := os.WriteFile(path, []byte(strings.Join(lines, "")), 0644); writeErr != nil {
+					return fmt.Errorf("failed to write %s: %v", path, writeErr)
+				}
+			}
+		}
+		if err == errPatchQuit {
+			break
+		}
+	}
+	return nil
+}
@@ -6,7 +6,7 @@ func PullRemote(remoteName, targetBranch string) error {
 		return err
 	}
 
-	err = MergeBranch(targetBranch, targetBranch, remoteName)
+	err = MergeBranch(targetBranch, targetBranch, remoteName, MergeOptions{})
 	if err != nil {
 		return err
 	}
@@ -5,31 +5,37 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/airbornharsh/hit/internal/apis"
 	"github.com/airbornharsh/hit/internal/go_types"
-	"github.com/airbornharsh/hit/internal/storage"
 )
 
-func Push(remoteName, branchName string) error {
-	config, err := storage.GetConfig()
+// Push uploads branchName to the remote. The pre-push hook runs first
+// unless noVerify is set.
+func Push(remoteName, branchName string, noVerify bool) error {
+	remote, err := GetRemoteURL(remoteName)
 	if err != nil {
 		return err
 	}
 
-	_, ok := config.Remotes[remoteName]
-	if !ok {
-		return fmt.Errorf("remote %s not found", remoteName)
-	}
-
-	remote := config.Remotes[remoteName].URL
-
-	err = apis.UploadAllFiles(remote)
+	headExists, currentCommit, err := apis.GetHeadCommitHash(remote, branchName)
 	if err != nil {
 		return err
 	}
 
-	headExists, currentCommit, err := apis.GetHeadCommitHash(remote, branchName)
+	if !noVerify {
+		localCommit, err := getLocalBranchCommit(branchName)
+		if err != nil {
+			return fmt.Errorf("branch '%s' not found", branchName)
+		}
+		ref := "refs/heads/" + branchName
+		if err := runPrePushHook(remoteName, remote, []string{prePushLine(ref, localCommit, ref, currentCommit)}); err != nil {
+			return err
+		}
+	}
+
+	err = apis.UploadAllFiles(remote)
 	if err != nil {
 		return err
 	}
@@ -101,3 +107,15 @@ func Push(remoteName, branchName string) error {
 
 	return nil
 }
+
+// prePushLine formats one ref update for the pre-push hook's stdin.
+func prePushLine(localRef, localHash, remoteRef, remoteHash string) string {
+	if remoteHash == "" {
+		remoteHash = "0000000000000000000000000000000000000000"
+	}
+	return fmt.Sprintf("%s %s %s %s", localRef, localHash, remoteRef, remoteHash)
+}
+
+func runPrePushHook(remoteName, url string, lines []string) error {
+	return RunHook(HookPrePush, strings.Join(lines, "\n")+"\n", remoteName, url)
+}
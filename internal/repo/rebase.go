@@ -0,0 +1,768 @@
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/airbornharsh/hit/internal/config"
+	"github.com/airbornharsh/hit/internal/go_types"
+	"github.com/airbornharsh/hit/internal/storage"
+)
+
+// Actions a rebase step can carry out
+const (
+	RebaseActionPick   = "pick"   // apply the commit
+	RebaseActionReword = "reword" // apply the commit and edit its message
+	RebaseActionEdit   = "edit"   // apply the commit and stop to amend it
+	RebaseActionSquash = "squash" // fold into the previous commit, combining messages
+	RebaseActionFixup  = "fixup"  // fold into the previous commit, keeping its message
+	RebaseActionDrop   = "drop"   // leave the commit out
+)
+
+// Reasons a rebase stops
+const (
+	rebaseStopConflict = "conflict"
+	rebaseStopEdit     = "edit"
+)
+
+// ErrStoppedOnConflicts is returned when a rebase, cherry-pick or revert
+// stops for the user to resolve conflicts. Its state is saved so that it can
+// be continued.
+var ErrStoppedOnConflicts = errors.New("stopped on conflicts")
+
+// RebaseStep is one entry of the rebase plan.
+type RebaseStep struct {
+	Action string          `json:"action"`
+	Commit go_types.Commit `json:"commit"`
+}
+
+// RebaseState is the progress of a rebase, kept in .hit/rebase.json while
+// it runs. Todo[0] is the step being applied when the rebase stops.
+type RebaseState struct {
+	Branch   string            `json:"branch"`
+	OrigHead string            `json:"origHead"`
+	Onto     string            `json:"onto"`
+	OrigLog  []go_types.Commit `json:"origLog"`
+	Todo     []RebaseStep      `json:"todo"`
+	Stop     string            `json:"stop,omitempty"`  // why the rebase stopped at Todo[0]
+	Amend    bool              `json:"amend,omitempty"` // the stopped step folds into HEAD
+}
+
+// RebaseOptions controls Rebase.
+type RebaseOptions struct {
+	Onto        string // replay onto this revision instead of upstream
+	Interactive bool   // let the user edit the plan before it runs
+	Autosquash  bool   // move "fixup!" and "squash!" commits after their targets
+}
+
+func rebaseStatePath() string {
+	return filepath.Join(".hit", "rebase.json")
+}
+
+// LoadRebaseState returns the rebase in progress, or nil when there is none.
+func LoadRebaseState() (*RebaseState, error) {
+	data, err := os.ReadFile(rebaseStatePath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state RebaseState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse rebase state: %v", err)
+	}
+	return &state, nil
+}
+
+func (s *RebaseState) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal rebase state: %v", err)
+	}
+	return os.WriteFile(rebaseStatePath(), data, 0644)
+}
+
+// IsRebaseInProgress reports whether a rebase has stopped and is waiting for
+// --continue, --skip or --abort.
+func IsRebaseInProgress() bool {
+	state, err := LoadRebaseState()
+	return err == nil && state != nil
+}
+
+// Rebase replays the commits of the current branch that are not in upstream
+// onto opts.Onto, or onto upstream itself when that is empty.
+func Rebase(upstream string, opts RebaseOptions) error {
+	if IsRebaseInProgress() {
+		return fmt.Errorf("a rebase is already in progress; run 'hit rebase --continue', '--skip' or '--abort'")
+	}
+	if cr, _ := LoadConflictResolution(); cr != nil {
+		return fmt.Errorf("cannot rebase: you have unfinished merge or conflict state")
+	}
+	if err := ensureCleanWorkTree(); err != nil {
+		return fmt.Errorf("cannot rebase: %v", err)
+	}
+
+	branch, err := storage.GetBranch()
+	if err != nil {
+		return err
+	}
+	head, err := getLocalBranchCommit(branch)
+	if err != nil {
+		return fmt.Errorf("failed to get current branch commit: %v", err)
+	}
+	upstreamHash, err := ResolveRevision(upstream)
+	if err != nil {
+		return err
+	}
+	ontoLabel := upstream
+	ontoHash := upstreamHash
+	if opts.Onto != "" {
+		ontoLabel = opts.Onto
+		if ontoHash, err = ResolveRevision(opts.Onto); err != nil {
+			return err
+		}
+	}
+
+	graph, err := loadCommitGraph()
+	if err != nil {
+		return fmt.Errorf("failed to load commit graph: %v", err)
+	}
+	commits := commitsToReplay(graph, head, upstreamHash)
+
+	todo := make([]RebaseStep, 0, len(commits))
+	for _, c := range commits {
+		todo = append(todo, RebaseStep{Action: RebaseActionPick, Commit: c})
+	}
+	if opts.Autosquash {
+		todo = autosquashSteps(todo)
+	}
+
+	if opts.Interactive {
+		if todo, err = editRebaseTodo(todo, commits, shortHash(ontoHash)); err != nil {
+			return err
+		}
+		if len(todo) == 0 {
+			fmt.Println("Nothing to do")
+			return nil
+		}
+	} else if !opts.Autosquash && (isLinearOn(commits, ontoHash) || (len(commits) == 0 && head == ontoHash)) {
+		fmt.Println("Current branch is up to date.")
+		return nil
+	}
+
+	origLog, _ := storage.GetHeadCommits(branch)
+	state := &RebaseState{
+		Branch:   branch,
+		OrigHead: head,
+		Onto:     ontoHash,
+		OrigLog:  origLog,
+		Todo:     todo,
+	}
+	if err := state.save(); err != nil {
+		return err
+	}
+
+	if err := resetBranchTo(branch, ontoHash, graph, "rebase (start): checkout "+ontoLabel); err != nil {
+		return err
+	}
+
+	return runRebase(state)
+}
+
+// ContinueRebase commits the resolved step the rebase stopped at and carries
+// on with the rest of the plan.
+func ContinueRebase() error {
+	state, err := LoadRebaseState()
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return fmt.Errorf("no rebase in progress")
+	}
+
+	cr, err := LoadConflictResolution()
+	if err != nil {
+		return err
+	}
+	if cr != nil && cr.HasUnresolvedConflicts() {
+		var paths []string
+		for _, conflict := range cr.GetUnresolvedConflicts() {
+			paths = append(paths, conflict.FilePath)
+		}
+		return fmt.Errorf("unresolved conflicts in %v; resolve them and run 'hit add' first", paths)
+	}
+
+	if len(state.Todo) > 0 {
+		step := state.Todo[0]
+		switch {
+		case state.Stop == rebaseStopEdit:
+			// Staged changes made while stopped are folded into the edited commit.
+			err = amendIndex(state.Branch, "", "rebase (continue): "+firstLine(step.Commit.Message))
+		case state.Amend:
+			message := ""
+			if cr != nil {
+				message = cr.Message
+			}
+			err = amendIndex(state.Branch, message, "rebase (continue): "+firstLine(step.Commit.Message))
+		default:
+			message := step.Commit.Message
+			if cr != nil && cr.Message != "" {
+				message = cr.Message
+			}
+			err = commitIndex(state.Branch, message, CommitAuthor(step.Commit), "rebase (continue): "+firstLine(message))
+		}
+		if err != nil {
+			return err
+		}
+		state.Todo = state.Todo[1:]
+	}
+	state.Stop = ""
+	state.Amend = false
+	if cr != nil {
+		if err := ClearConflictResolution(); err != nil {
+			return err
+		}
+	}
+
+	return runRebase(state)
+}
+
+// SkipRebase drops the step the rebase stopped at and carries on.
+func SkipRebase() error {
+	state, err := LoadRebaseState()
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return fmt.Errorf("no rebase in progress")
+	}
+
+	if err := discardStepChanges(state.Branch); err != nil {
+		return err
+	}
+	if len(state.Todo) > 0 {
+		fmt.Printf("Skipped %s %s\n", shortHash(state.Todo[0].Commit.Hash), firstLine(state.Todo[0].Commit.Message))
+		state.Todo = state.Todo[1:]
+	}
+	state.Stop = ""
+	state.Amend = false
+
+	return runRebase(state)
+}
+
+// AbortRebase returns the branch, working tree and index to where they were
+// before the rebase started.
+func AbortRebase() error {
+	state, err := LoadRebaseState()
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return fmt.Errorf("no rebase in progress")
+	}
+
+	if err := discardStepChanges(state.Branch); err != nil {
+		return err
+	}
+	if err := updateBranchRef(state.Branch, state.OrigHead, "rebase (abort): returning to refs/heads/"+state.Branch); err != nil {
+		return err
+	}
+	if err := storage.UpdateHeadCommits(state.Branch, state.OrigLog); err != nil {
+		return fmt.Errorf("failed to restore branch log: %v", err)
+	}
+	if err := storage.UpdateWorkingDirectoryAndIndexFromCommit(state.OrigHead); err != nil {
+		return fmt.Errorf("failed to update working directory and index: %v", err)
+	}
+
+	return os.Remove(rebaseStatePath())
+}
+
+// runRebase applies the remaining steps, stopping at the first conflict.
+func runRebase(state *RebaseState) error {
+	for len(state.Todo) > 0 {
+		if err := state.save(); err != nil {
+			return err
+		}
+
+		step := state.Todo[0]
+		stopped, err := applyRebaseStep(state, step)
+		if err != nil {
+			return err
+		}
+		if stopped {
+			if err := state.save(); err != nil {
+				return err
+			}
+			if state.Stop == rebaseStopConflict {
+				return ErrStoppedOnConflicts
+			}
+			return nil
+		}
+		state.Todo = state.Todo[1:]
+	}
+
+	head, err := getLocalBranchCommit(state.Branch)
+	if err != nil {
+		return err
+	}
+	if err := AppendReflog(state.Branch, head, head, "rebase (finish): returning to refs/heads/"+state.Branch); err != nil {
+		return err
+	}
+	if err := os.Remove(rebaseStatePath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	fmt.Printf("Successfully rebased and updated refs/heads/%s.\n", state.Branch)
+	return nil
+}
+
+// applyRebaseStep carries out one step. It reports whether the rebase
+// stopped for the user.
+func applyRebaseStep(state *RebaseState, step RebaseStep) (bool, error) {
+	c := step.Commit
+	message := c.Message
+	amend := false
+
+	switch step.Action {
+	case RebaseActionDrop:
+		return false, nil
+	case RebaseActionReword:
+		edited, err := editMessage(c.Message)
+		if err != nil {
+			return false, err
+		}
+		message = edited
+	case RebaseActionSquash, RebaseActionFixup:
+		head, err := branchHeadCommit(state.Branch)
+		if err != nil {
+			return false, err
+		}
+		amend = true
+		message = head.Message
+		if step.Action == RebaseActionSquash {
+			combined, err := editMessage(head.Message + "\n\n" + c.Message)
+			if err != nil {
+				return false, err
+			}
+			message = combined
+		}
+	}
+
+	conflicted, err := pickCommit(state.Branch, c, message, amend, "rebase ("+step.Action+"): "+firstLine(message))
+	if err != nil {
+		return false, err
+	}
+	if conflicted {
+		state.Stop = rebaseStopConflict
+		state.Amend = amend
+		fmt.Printf("Could not apply %s... %s\n", shortHash(c.Hash), firstLine(c.Message))
+		fmt.Println("Resolve all conflicts manually, mark them as resolved with 'hit add <file>',")
+		fmt.Println("then run 'hit rebase --continue'. To skip this commit run 'hit rebase --skip';")
+		fmt.Println("to go back to where you started run 'hit rebase --abort'.")
+		return true, nil
+	}
+
+	if step.Action == RebaseActionEdit {
+		state.Stop = rebaseStopEdit
+		fmt.Printf("Stopped at %s... %s\n", shortHash(c.Hash), firstLine(c.Message))
+		fmt.Println("Make your changes and stage them with 'hit add', then run 'hit rebase --continue'.")
+		return true, nil
+	}
+	return false, nil
+}
+
+// pickCommit applies the changes c made to its first parent onto the head
+// of branch and commits them with message, replacing the head commit when
+// amend is set. It reports whether the commit stopped on conflicts.
+func pickCommit(branch string, c go_types.Commit, message string, amend bool, reflogMessage string) (bool, error) {
+	head, err := getLocalBranchCommit(branch)
+	if err != nil {
+		return false, err
+	}
+
+	// Unchanged commits on an unchanged parent are reused as they are.
+	if !amend && c.Parent == head && message == c.Message {
+		return false, fastForwardCommit(branch, c, reflogMessage)
+	}
+
+	change := treeChange{
+		Base:        c.Parent,
+		Target:      c.Hash,
+		BaseLabel:   "parent of " + shortHash(c.Hash),
+		TargetLabel: shortHash(c.Hash) + " (" + firstLine(c.Message) + ")",
+	}
+	return applyChange(branch, change, message, CommitAuthor(c), amend, reflogMessage)
+}
+
+// treeChange is the difference between two commits, applied by
+// applyChange. An empty Base or Target stands for the empty tree.
+type treeChange struct {
+	Base        string
+	Target      string
+	BaseLabel   string
+	TargetLabel string
+}
+
+// applyChange merges change onto the head of branch and commits the result
+// with message and author (the current author when zero), replacing the head commit when amend is set. On
+// conflicts the merge result is left in the working tree with the conflict
+// state saved, and true is returned.
+func applyChange(branch string, change treeChange, message string, author Identity, amend bool, reflogMessage string) (bool, error) {
+	head, err := getLocalBranchCommit(branch)
+	if err != nil {
+		return false, err
+	}
+	headTree, err := storage.GetCommitTree(head)
+	if err != nil {
+		return false, fmt.Errorf("failed to get head tree: %v", err)
+	}
+	baseTree, err := changeTree(change.Base)
+	if err != nil {
+		return false, err
+	}
+	targetTree, err := changeTree(change.Target)
+	if err != nil {
+		return false, err
+	}
+
+	markers := ConflictMarkers{
+		Current:  "HEAD",
+		Base:     change.BaseLabel,
+		Target:   change.TargetLabel,
+		ShowBase: config.GetString("merge.conflictStyle", ConflictStyleMerge) == ConflictStyleDiff3,
+	}
+	cr := CreateConflictResolution()
+	cr.Message = message
+
+	result, err := mergeTrees(headTree, targetTree, baseTree, markers, "", cr)
+	if err != nil {
+		return false, err
+	}
+	if result.HasConflicts {
+		if err := updateWorkingDirectoryWithConflictsAndNonConflicts(cr, result.NonConflict, result.Deleted); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	if amend {
+		_, err = amendCommit(branch, result.Tree.Entries, message, Identity{}, false, reflogMessage)
+		return false, err
+	}
+
+	if maps.Equal(result.Tree.Entries, headTree.Entries) {
+		fmt.Printf("dropping %s -- patch contents already upstream\n", change.TargetLabel)
+		return false, nil
+	}
+
+	_, err = recordCommit(branch, newCommit{Entries: result.Tree.Entries, Parent: head, Message: message, Author: author}, reflogMessage)
+	return false, err
+}
+
+// changeTree returns the tree of commit, or the empty tree for "".
+func changeTree(commit string) (*go_types.Tree, error) {
+	if commit == "" {
+		return &go_types.Tree{Entries: map[string]string{}}, nil
+	}
+	tree, err := storage.GetCommitTree(commit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree of %s: %v", shortHash(commit), err)
+	}
+	return tree, nil
+}
+
+// fastForwardCommit moves branch onto c, which must be a child of its head.
+func fastForwardCommit(branch string, c go_types.Commit, reflogMessage string) error {
+	commits, err := storage.GetHeadCommits(branch)
+	if err != nil {
+		commits = []go_types.Commit{}
+	}
+	commits = append(commits, c)
+	if err := storage.UpdateHeadCommits(branch, commits); err != nil {
+		return fmt.Errorf("failed to update branch log: %v", err)
+	}
+	if err := updateBranchRef(branch, c.Hash, reflogMessage); err != nil {
+		return err
+	}
+	return storage.UpdateWorkingDirectoryAndIndexFromCommit(c.Hash)
+}
+
+// branchHeadCommit returns the logged commit the branch points at.
+func branchHeadCommit(branch string) (*go_types.Commit, error) {
+	head, err := getLocalBranchCommit(branch)
+	if err != nil {
+		return nil, err
+	}
+	commits, err := storage.GetHeadCommits(branch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read branch log: %v", err)
+	}
+	for _, c := range commits {
+		if c.Hash == head {
+			return &c, nil
+		}
+	}
+	return nil, fmt.Errorf("commit %s not found in the log of %s", shortHash(head), branch)
+}
+
+// amendCommit replaces the head commit of branch with one holding entries
+// and message, keeping its parents. An empty message keeps the old one, and
+// a zero author the old author.
+func amendCommit(branch string, entries map[string]string, message string, author Identity, sign bool, reflogMessage string) (*go_types.Commit, error) {
+	head, err := branchHeadCommit(branch)
+	if err != nil {
+		return nil, err
+	}
+	if message == "" {
+		message = head.Message
+	}
+	if author.Name == "" {
+		author = CommitAuthor(*head)
+	}
+
+	commits, err := storage.GetHeadCommits(branch)
+	if err != nil {
+		return nil, err
+	}
+	commits = slices.DeleteFunc(commits, func(c go_types.Commit) bool { return c.Hash == head.Hash })
+	if err := storage.UpdateHeadCommits(branch, commits); err != nil {
+		return nil, fmt.Errorf("failed to update branch log: %v", err)
+	}
+
+	return recordCommit(branch, newCommit{
+		Entries:     entries,
+		Parent:      head.Parent,
+		OtherParent: head.OtherParent,
+		Message:     message,
+		Author:      author,
+		Sign:        sign,
+	}, reflogMessage)
+}
+
+// amendIndex replaces the head commit of branch with the index contents.
+func amendIndex(branch, message, reflogMessage string) error {
+	_, err := amendCommit(branch, readIndexEntries(), message, Identity{}, false, reflogMessage)
+	return err
+}
+
+// commitIndex commits the index on top of branch. Nothing is committed when
+// the index matches the head commit.
+func commitIndex(branch, message string, author Identity, reflogMessage string) error {
+	head, err := getLocalBranchCommit(branch)
+	if err != nil {
+		return err
+	}
+	headTree, err := storage.GetCommitTree(head)
+	if err != nil {
+		return fmt.Errorf("failed to get head tree: %v", err)
+	}
+
+	entries := readIndexEntries()
+	if maps.Equal(entries, headTree.Entries) {
+		return nil
+	}
+	_, err = recordCommit(branch, newCommit{Entries: entries, Parent: head, Message: message, Author: author}, reflogMessage)
+	return err
+}
+
+// readIndexEntries returns the staged paths and their object hashes.
+func readIndexEntries() map[string]string {
+	index := &go_types.Index{Entries: make(map[string]string)}
+	if data, err := os.ReadFile(filepath.Join(".hit", "index.json")); err == nil {
+		_ = json.Unmarshal(data, index)
+	}
+	entries := make(map[string]string)
+	for path, hash := range index.Entries {
+		entries[filepath.ToSlash(path)] = hash
+	}
+	return entries
+}
+
+// writeIndexEntries replaces the staged paths and marks the index changed.
+func writeIndexEntries(entries map[string]string) error {
+	index := go_types.Index{Entries: entries, Changed: true}
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal index: %v", err)
+	}
+	return os.WriteFile(filepath.Join(".hit", "index.json"), data, 0644)
+}
+
+// newCommit describes a commit for recordCommit to create. A zero Author is
+// the current author.
+type newCommit struct {
+	Entries     map[string]string
+	Parent      string
+	OtherParent string
+	Message     string
+	Author      Identity
+	Sign        bool
+}
+
+// recordCommit stores nc as a commit on top of branch, adds it to the
+// branch log, moves the branch and checks it out.
+func recordCommit(branch string, nc newCommit, reflogMessage string) (*go_types.Commit, error) {
+	treeHash, err := storeTree(&go_types.Tree{Entries: nc.Entries, Parent: nc.Parent})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store tree: %v", err)
+	}
+
+	commit := go_types.Commit{
+		Hash:        treeHash,
+		Parent:      nc.Parent,
+		OtherParent: nc.OtherParent,
+		Message:     nc.Message,
+	}
+	if err := StampCommit(&commit, nc.Author); err != nil {
+		return nil, err
+	}
+	if nc.Sign {
+		if err := SignCommit(&commit); err != nil {
+			return nil, fmt.Errorf("failed to sign commit: %v", err)
+		}
+	}
+
+	commits, err := storage.GetHeadCommits(branch)
+	if err != nil {
+		commits = []go_types.Commit{}
+	}
+	commits = append(commits, commit)
+	if err := storage.UpdateHeadCommits(branch, commits); err != nil {
+		return nil, fmt.Errorf("failed to update branch log: %v", err)
+	}
+
+	if err := updateBranchRef(branch, commit.Hash, reflogMessage); err != nil {
+		return nil, err
+	}
+	if err := storage.UpdateWorkingDirectoryAndIndexFromCommit(commit.Hash); err != nil {
+		return nil, fmt.Errorf("failed to update working directory and index: %v", err)
+	}
+	return &commit, nil
+}
+
+// resetBranchTo moves branch to commitHash, rewrites its log to the history
+// of that commit and checks it out.
+func resetBranchTo(branch, commitHash string, graph map[string]go_types.Commit, reflogMessage string) error {
+	var history []go_types.Commit
+	for hash := range reachableCommits(graph, commitHash) {
+		if c, ok := graph[hash]; ok {
+			history = append(history, c)
+		}
+	}
+	slices.SortFunc(history, func(a, b go_types.Commit) int {
+		if a.CommittedAt().Before(b.CommittedAt()) {
+			return -1
+		} else if a.CommittedAt().After(b.CommittedAt()) {
+			return 1
+		}
+		return 0
+	})
+	if err := storage.UpdateHeadCommits(branch, history); err != nil {
+		return fmt.Errorf("failed to update branch log: %v", err)
+	}
+
+	if err := updateBranchRef(branch, commitHash, reflogMessage); err != nil {
+		return err
+	}
+	if err := storage.UpdateWorkingDirectoryAndIndexFromCommit(commitHash); err != nil {
+		return fmt.Errorf("failed to update working directory and index: %v", err)
+	}
+	return nil
+}
+
+// discardStepChanges throws away a half-applied step, putting the working
+// tree and index back to the head of branch.
+func discardStepChanges(branch string) error {
+	if cr, _ := LoadConflictResolution(); cr != nil {
+		if cr.OrigIndex != nil {
+			if err := AbortMerge(); err != nil {
+				return err
+			}
+		} else if err := ClearConflictResolution(); err != nil {
+			return err
+		}
+	}
+
+	head, err := getLocalBranchCommit(branch)
+	if err != nil {
+		return err
+	}
+	return storage.UpdateWorkingDirectoryAndIndexFromCommit(head)
+}
+
+// commitsToReplay lists the non-merge commits reachable from head but not
+// from upstream, parents before children.
+func commitsToReplay(graph map[string]go_types.Commit, head, upstream string) []go_types.Commit {
+	excluded := reachableCommits(graph, upstream)
+
+	var ordered []go_types.Commit
+	visited := make(map[string]bool)
+	var visit func(hash string)
+	visit = func(hash string) {
+		if visited[hash] || excluded[hash] {
+			return
+		}
+		visited[hash] = true
+		for _, parent := range commitParents(graph, hash) {
+			visit(parent)
+		}
+		if c, ok := graph[hash]; ok && c.OtherParent == "" {
+			ordered = append(ordered, c)
+		}
+	}
+	visit(head)
+
+	return ordered
+}
+
+// isLinearOn reports whether commits already form a single chain on top of
+// base, in which case replaying them would change nothing.
+func isLinearOn(commits []go_types.Commit, base string) bool {
+	if len(commits) == 0 {
+		return false
+	}
+	parent := base
+	for _, c := range commits {
+		if c.Parent != parent {
+			return false
+		}
+		parent = c.Hash
+	}
+	return true
+}
+
+// ensureCleanWorkTree fails when the working tree or index differ from the
+// current commit.
+func ensureCleanWorkTree() error {
+	dirty, err := hasUncommittedChanges()
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("you have unstaged changes")
+	}
+
+	index := &go_types.Index{Entries: make(map[string]string)}
+	if data, err := os.ReadFile(filepath.Join(".hit", "index.json")); err == nil {
+		_ = json.Unmarshal(data, index)
+	}
+	headTree, err := storage.GetHeadTree()
+	if err != nil {
+		return nil
+	}
+	if !maps.Equal(index.Entries, headTree.Entries) {
+		return fmt.Errorf("your index contains uncommitted changes")
+	}
+	return nil
+}
+
+func firstLine(message string) string {
+	line, _, _ := strings.Cut(message, "\n")
+	return line
+}
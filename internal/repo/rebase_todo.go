@@ -0,0 +1,162 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/airbornharsh/hit/internal/go_types"
+	"github.com/airbornharsh/hit/utils"
+)
+
+// rebaseActionAliases maps the words accepted in a todo file to actions.
+var rebaseActionAliases = map[string]string{
+	"pick": RebaseActionPick, "p": RebaseActionPick,
+	"reword": RebaseActionReword, "r": RebaseActionReword,
+	"edit": RebaseActionEdit, "e": RebaseActionEdit,
+	"squash": RebaseActionSquash, "s": RebaseActionSquash,
+	"fixup": RebaseActionFixup, "f": RebaseActionFixup,
+	"drop": RebaseActionDrop, "d": RebaseActionDrop,
+}
+
+const rebaseTodoHelp = `
+# Rebase onto %s (%d command(s))
+#
+# Commands:
+# p, pick <commit> = use commit
+# r, reword <commit> = use commit, but edit the commit message
+# e, edit <commit> = use commit, but stop for amending
+# s, squash <commit> = use commit, but meld into previous commit
+# f, fixup <commit> = like "squash", but discard this commit's message
+# d, drop <commit> = remove commit
+#
+# These lines can be re-ordered; they are executed from top to bottom.
+# If you remove a line here THAT COMMIT WILL BE LOST.
+# However, if you remove everything, the rebase will be aborted.
+`
+
+// editRebaseTodo writes the plan to .hit/rebase-todo, opens it in the
+// editor and parses the result. commits are the commits the plan may refer
+// to.
+func editRebaseTodo(todo []RebaseStep, commits []go_types.Commit, onto string) ([]RebaseStep, error) {
+	var b strings.Builder
+	for _, step := range todo {
+		fmt.Fprintf(&b, "%s %s %s\n", step.Action, shortHash(step.Commit.Hash), firstLine(step.Commit.Message))
+	}
+	fmt.Fprintf(&b, rebaseTodoHelp, onto, len(todo))
+
+	path := filepath.Join(".hit", "rebase-todo")
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write rebase todo: %v", err)
+	}
+	defer os.Remove(path)
+
+	if err := utils.LaunchEditor(path); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseRebaseTodo(string(data), commits)
+}
+
+// parseRebaseTodo reads an edited todo list. Each line is an action, a
+// commit hash or unique prefix and an optional subject that is ignored.
+func parseRebaseTodo(text string, commits []go_types.Commit) ([]RebaseStep, error) {
+	var steps []RebaseStep
+	for n, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		action, ok := rebaseActionAliases[fields[0]]
+		if !ok {
+			return nil, fmt.Errorf("line %d: unknown command '%s'", n+1, fields[0])
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: missing commit", n+1)
+		}
+
+		var match *go_types.Commit
+		for i := range commits {
+			if strings.HasPrefix(commits[i].Hash, fields[1]) {
+				if match != nil {
+					return nil, fmt.Errorf("line %d: ambiguous commit '%s'", n+1, fields[1])
+				}
+				match = &commits[i]
+			}
+		}
+		if match == nil {
+			return nil, fmt.Errorf("line %d: '%s' is not one of the commits being rebased", n+1, fields[1])
+		}
+
+		if (action == RebaseActionSquash || action == RebaseActionFixup) && len(steps) == 0 {
+			return nil, fmt.Errorf("line %d: cannot '%s' without a previous commit", n+1, action)
+		}
+		if action == RebaseActionDrop {
+			continue
+		}
+		steps = append(steps, RebaseStep{Action: action, Commit: *match})
+	}
+	return steps, nil
+}
+
+// autosquashSteps moves every "fixup! <subject>" and "squash! <subject>"
+// commit to just after the commit it names, by subject or hash prefix, and
+// turns it into a fixup or squash step.
+func autosquashSteps(todo []RebaseStep) []RebaseStep {
+	var base []RebaseStep
+	followers := make(map[string][]RebaseStep)
+
+	for _, step := range todo {
+		subject := firstLine(step.Commit.Message)
+		action := ""
+		target := ""
+		switch {
+		case strings.HasPrefix(subject, "fixup! "):
+			action, target = RebaseActionFixup, strings.TrimPrefix(subject, "fixup! ")
+		case strings.HasPrefix(subject, "squash! "):
+			action, target = RebaseActionSquash, strings.TrimPrefix(subject, "squash! ")
+		}
+
+		if action != "" {
+			if owner := autosquashTarget(base, target); owner != "" {
+				step.Action = action
+				followers[owner] = append(followers[owner], step)
+				continue
+			}
+		}
+		base = append(base, step)
+	}
+
+	result := make([]RebaseStep, 0, len(todo))
+	for _, step := range base {
+		result = append(result, step)
+		result = append(result, followers[step.Commit.Hash]...)
+	}
+	return result
+}
+
+// autosquashTarget finds the earlier step a fixup!/squash! subject refers to.
+func autosquashTarget(steps []RebaseStep, target string) string {
+	// A fixup of a fixup points at the same commit as the first one.
+	for strings.HasPrefix(target, "fixup! ") || strings.HasPrefix(target, "squash! ") {
+		_, target, _ = strings.Cut(target, " ")
+	}
+	for _, step := range steps {
+		subject := firstLine(step.Commit.Message)
+		if subject == target || (len(target) >= 4 && strings.HasPrefix(step.Commit.Hash, target)) {
+			return step.Commit.Hash
+		}
+	}
+	for _, step := range steps {
+		if strings.HasPrefix(firstLine(step.Commit.Message), target) {
+			return step.Commit.Hash
+		}
+	}
+	return ""
+}
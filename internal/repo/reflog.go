@@ -0,0 +1,84 @@
+package repo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/airbornharsh/hit/internal/go_types"
+)
+
+func reflogPath(branch string) string {
+	return filepath.Join(".hit", "reflog", "refs", "heads", branch)
+}
+
+// ReadReflog returns the recorded ref updates of a branch, oldest first.
+func ReadReflog(branch string) ([]go_types.ReflogEntry, error) {
+	data, err := os.ReadFile(reflogPath(branch))
+	if os.IsNotExist(err) {
+		return []go_types.ReflogEntry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []go_types.ReflogEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse reflog: %v", err)
+	}
+	return entries, nil
+}
+
+// AppendReflog records that branch moved from oldHash to newHash.
+func AppendReflog(branch, oldHash, newHash, message string) error {
+	entries, err := ReadReflog(branch)
+	if err != nil {
+		return err
+	}
+	entries = append(entries, go_types.ReflogEntry{
+		Old:       oldHash,
+		New:       newHash,
+		Message:   message,
+		Timestamp: go_types.TimeNow(),
+	})
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	path := reflogPath(branch)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// updateBranchRef points branch at newHash and records the move in the
+// reflog.
+func updateBranchRef(branch, newHash, message string) error {
+	oldHash, _ := getLocalBranchCommit(branch)
+	refPath := filepath.Join(".hit", "refs", "heads", branch)
+	if err := os.WriteFile(refPath, []byte(newHash), 0644); err != nil {
+		return fmt.Errorf("failed to update branch reference: %v", err)
+	}
+	if oldHash == newHash {
+		return nil
+	}
+	return AppendReflog(branch, oldHash, newHash, message)
+}
+
+// reflogEntryHash returns the commit branch pointed at n moves ago, as
+// written in the revision syntax branch@{n}.
+func reflogEntryHash(branch string, n int) (string, error) {
+	entries, err := ReadReflog(branch)
+	if err != nil {
+		return "", err
+	}
+	if n == 0 {
+		return getLocalBranchCommit(branch)
+	}
+	if n > len(entries) {
+		return "", fmt.Errorf("reflog of '%s' has only %d entries", branch, len(entries))
+	}
+	return entries[len(entries)-n].Old, nil
+}
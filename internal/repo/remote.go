@@ -1,12 +1,12 @@
 package repo
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/airbornharsh/hit/internal/config"
 	"github.com/airbornharsh/hit/internal/go_types"
 )
 
@@ -28,12 +28,14 @@ func AddRemote(name, url string) error {
 		return fmt.Errorf("remote '%s' already exists", name)
 	}
 
-	remotes.Remotes[name] = go_types.Remote{
-		Name: name,
-		URL:  url,
+	local, err := config.Load(config.Local)
+	if err != nil {
+		return err
 	}
-
-	return saveRemotes(remotes)
+	if err := local.Set("remote."+name+".url", url); err != nil {
+		return err
+	}
+	return local.Save()
 }
 
 func RemoveRemote(name string) error {
@@ -46,9 +48,14 @@ func RemoveRemote(name string) error {
 		return fmt.Errorf("remote '%s' does not exist", name)
 	}
 
-	delete(remotes.Remotes, name)
-
-	return saveRemotes(remotes)
+	local, err := config.Load(config.Local)
+	if err != nil {
+		return err
+	}
+	if !local.RemoveSubsection("remote", name) {
+		return fmt.Errorf("remote '%s' is set in the global or system config; remove it there", name)
+	}
+	return local.Save()
 }
 
 func ListRemotes() error {
@@ -83,41 +90,17 @@ func GetRemoteURL(name string) (string, error) {
 	return remote.URL, nil
 }
 
+// loadRemotes collects the remotes from every config file.
 func loadRemotes() (*go_types.RemoteConfig, error) {
-	configPath := filepath.Join(".hit", "config")
-
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		return &go_types.RemoteConfig{
-			Remotes: make(map[string]go_types.Remote),
-		}, nil
-	}
-
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, err
-	}
-
-	var config go_types.RemoteConfig
-	if err := json.Unmarshal(data, &config); err != nil {
-		return nil, err
+	remotes := &go_types.RemoteConfig{
+		Remotes: make(map[string]go_types.Remote),
 	}
-
-	if config.Remotes == nil {
-		config.Remotes = make(map[string]go_types.Remote)
-	}
-
-	return &config, nil
-}
-
-func saveRemotes(config *go_types.RemoteConfig) error {
-	configPath := filepath.Join(".hit", "config")
-
-	data, err := json.Marshal(config)
-	if err != nil {
-		return err
+	for _, name := range config.Subsections("remote") {
+		if url, ok := config.Get("remote." + name + ".url"); ok {
+			remotes.Remotes[name] = go_types.Remote{Name: name, URL: url}
+		}
 	}
-
-	return os.WriteFile(configPath, data, 0644)
+	return remotes, nil
 }
 
 func isValidRemoteURL(url string) bool {
@@ -0,0 +1,156 @@
+package repo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/airbornharsh/hit/internal/storage"
+)
+
+// rerereEntry is one recorded resolution. Preimage is the conflicted file in
+// normalised form, Postimage the content that was staged for it.
+type rerereEntry struct {
+	Path      string `json:"path"`
+	Preimage  string `json:"preimage"`
+	Postimage string `json:"postimage"`
+}
+
+func rerereDir() string {
+	return filepath.Join(".hit", "rr-cache")
+}
+
+// RerereEnabled reports whether conflict resolutions are being recorded.
+// Recording is opt-in and turned on by the presence of .hit/rr-cache.
+func RerereEnabled() bool {
+	info, err := os.Stat(rerereDir())
+	return err == nil && info.IsDir()
+}
+
+// EnableRerere turns on recording and reuse of conflict resolutions.
+func EnableRerere() error {
+	return os.MkdirAll(rerereDir(), 0755)
+}
+
+// DisableRerere turns recording off and drops every recorded resolution.
+func DisableRerere() error {
+	return os.RemoveAll(rerereDir())
+}
+
+// ClearRerere drops every recorded resolution but keeps recording enabled.
+func ClearRerere() error {
+	if !RerereEnabled() {
+		return nil
+	}
+	if err := os.RemoveAll(rerereDir()); err != nil {
+		return err
+	}
+	return EnableRerere()
+}
+
+// conflictPreimage computes the normalised conflicted content of a file and
+// the ID of its conflicts. Branch labels and the base section are left out
+// and the two sides of every conflict are put in a fixed order, so the same
+// conflict gets the same ID whichever way round the branches are merged.
+func conflictPreimage(currentHash, targetHash, ancestorHash string) (string, string, bool, error) {
+	contents := make([]string, 3)
+	for i, hash := range []string{currentHash, targetHash, ancestorHash} {
+		content, err := storage.GetFileContentFromHash(hash)
+		if err != nil {
+			return "", "", false, err
+		}
+		contents[i] = content
+	}
+
+	chunks := mergeLines(splitLines(contents[2]), splitLines(contents[0]), splitLines(contents[1]))
+	if !hasConflictChunks(chunks) {
+		return "", "", false, nil
+	}
+
+	var conflicts []mergeChunk
+	for i, c := range chunks {
+		if !c.Conflict {
+			continue
+		}
+		if strings.Join(c.Ours, "") > strings.Join(c.Theirs, "") {
+			c.Ours, c.Theirs = c.Theirs, c.Ours
+		}
+		c.Base = nil
+		chunks[i] = c
+		conflicts = append(conflicts, c)
+	}
+
+	id := storage.Hash([]byte(createConflictMarkers(conflicts, ConflictMarkers{})))
+	return id, createConflictMarkers(chunks, ConflictMarkers{}), true, nil
+}
+
+func loadRerereEntry(id string) (*rerereEntry, error) {
+	data, err := os.ReadFile(filepath.Join(rerereDir(), id))
+	if err != nil {
+		return nil, err
+	}
+	var entry rerereEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// rerereResolve looks for a recorded resolution of the conflicts between the
+// given versions. The recorded change from preimage to postimage is replayed
+// onto the current preimage, so parts of the file outside the conflicts may
+// differ from the recorded merge.
+func rerereResolve(currentHash, targetHash, ancestorHash string) (string, bool) {
+	if !RerereEnabled() {
+		return "", false
+	}
+
+	id, preimage, conflicted, err := conflictPreimage(currentHash, targetHash, ancestorHash)
+	if err != nil || !conflicted {
+		return "", false
+	}
+
+	entry, err := loadRerereEntry(id)
+	if err != nil {
+		return "", false
+	}
+
+	chunks := mergeLines(splitLines(entry.Preimage), splitLines(entry.Postimage), splitLines(preimage))
+	if hasConflictChunks(chunks) {
+		return "", false
+	}
+
+	var resolved strings.Builder
+	for _, c := range chunks {
+		resolved.WriteString(strings.Join(c.Lines, ""))
+	}
+	return resolved.String(), true
+}
+
+// rerereRecord stores the staged content of a conflicted file as the
+// resolution of its conflicts.
+func rerereRecord(conflict ConflictFile, resolved []byte) error {
+	if !RerereEnabled() {
+		return nil
+	}
+	if conflict.Kind != ConflictKindContent && conflict.Kind != ConflictKindAddAdd {
+		return nil
+	}
+
+	id, preimage, conflicted, err := conflictPreimage(conflict.CurrentHash, conflict.TargetHash, conflict.AncestorHash)
+	if err != nil || !conflicted {
+		return err
+	}
+
+	data, err := json.MarshalIndent(rerereEntry{
+		Path:      conflict.FilePath,
+		Preimage:  preimage,
+		Postimage: string(resolved),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal resolution: %v", err)
+	}
+	return os.WriteFile(filepath.Join(rerereDir(), id), data, 0644)
+}
@@ -0,0 +1,111 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/airbornharsh/hit/internal/storage"
+)
+
+// ResolveRevision turns a revision into a commit hash. It accepts HEAD, a
+// local branch, a tag, a remote-tracking branch written as <remote>/<branch>,
+// a reflog entry written as <branch>@{n}, a full commit hash or an
+// unambiguous hash prefix.
+func ResolveRevision(rev string) (string, error) {
+	if rev == "" {
+		return "", fmt.Errorf("empty revision")
+	}
+
+	if name, n, ok := parseReflogRevision(rev); ok {
+		if name == "" || name == "HEAD" {
+			branch, err := storage.GetBranch()
+			if err != nil {
+				return "", err
+			}
+			name = branch
+		}
+		return reflogEntryHash(name, n)
+	}
+
+	if rev == "HEAD" {
+		branch, err := storage.GetBranch()
+		if err != nil {
+			return "", err
+		}
+		return getLocalBranchCommit(branch)
+	}
+
+	if commit, err := getLocalBranchCommit(rev); err == nil {
+		return commit, nil
+	}
+
+	if commit, err := tagCommit(rev); err == nil {
+		return commit, nil
+	}
+
+	if remoteName, branchName, ok := strings.Cut(rev, "/"); ok {
+		if commit, err := getRemoteBranchCommit(remoteName, branchName); err == nil {
+			return commit, nil
+		}
+	}
+
+	return resolveCommitHash(rev)
+}
+
+// parseReflogRevision splits <branch>@{n} into its parts.
+func parseReflogRevision(rev string) (string, int, bool) {
+	at := strings.LastIndex(rev, "@{")
+	if at < 0 || !strings.HasSuffix(rev, "}") {
+		return "", 0, false
+	}
+	n, err := strconv.Atoi(rev[at+2 : len(rev)-1])
+	if err != nil || n < 0 {
+		return "", 0, false
+	}
+	return rev[:at], n, true
+}
+
+// resolveCommitHash expands a full or abbreviated hash to a known commit.
+func resolveCommitHash(prefix string) (string, error) {
+	if len(prefix) < 4 || strings.Trim(prefix, "0123456789abcdef") != "" {
+		return "", fmt.Errorf("unknown revision: %s", prefix)
+	}
+
+	graph, err := loadCommitGraph()
+	if err != nil {
+		return "", err
+	}
+
+	var matches []string
+	for hash := range graph {
+		if strings.HasPrefix(hash, prefix) {
+			matches = append(matches, hash)
+		}
+	}
+
+	if len(matches) == 0 && len(prefix) == 40 {
+		_, _, objectPath, err := storage.HashInfo(prefix)
+		if err == nil {
+			if _, err := os.Stat(objectPath); err == nil {
+				return prefix, nil
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("unknown revision: %s", prefix)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("ambiguous revision: %s matches %d commits", prefix, len(matches))
+	}
+}
+
+// RevisionExists reports whether rev resolves to a commit.
+func RevisionExists(rev string) bool {
+	_, err := ResolveRevision(rev)
+	return err == nil
+}
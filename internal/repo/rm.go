@@ -0,0 +1,215 @@
+package repo
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/airbornharsh/hit/internal/storage"
+)
+
+// RemoveOptions controls how RemovePaths untracks files.
+type RemoveOptions struct {
+	Cached    bool // only remove from the index, keeping the working tree files
+	Recursive bool // allow directories, removing every tracked file below them
+	Force     bool // skip the checks that protect uncommitted changes
+}
+
+// indexPathsUnder returns the index paths equal to path or below it.
+func indexPathsUnder(entries map[string]string, path string) []string {
+	var paths []string
+	for entry := range entries {
+		if path == "." || entry == path || strings.HasPrefix(entry, path+"/") {
+			paths = append(paths, entry)
+		}
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+// worktreeHash returns the hash of path's working tree content, or "" when
+// the file is missing.
+func worktreeHash(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return storage.Hash(content)
+}
+
+// RemovePaths removes the tracked files matching paths from the index and,
+// unless opts.Cached is set, from the working tree. Directories need
+// opts.Recursive. Without opts.Force nothing is removed if it would lose
+// changes that are not committed.
+func RemovePaths(paths []string, opts RemoveOptions) error {
+	entries := readIndexEntries()
+	head := map[string]string{}
+	if tree, err := storage.GetHeadTree(); err == nil {
+		head = tree.Entries
+	}
+
+	var targets []string
+	for _, p := range paths {
+		rel, err := repoPath(p)
+		if err != nil {
+			return err
+		}
+		matched := indexPathsUnder(entries, rel)
+		if len(matched) == 0 {
+			return fmt.Errorf("pathspec '%s' did not match any files", p)
+		}
+		if _, tracked := entries[rel]; !tracked && !opts.Recursive {
+			return fmt.Errorf("not removing '%s' recursively without -r", p)
+		}
+		targets = append(targets, matched...)
+	}
+	slices.Sort(targets)
+	targets = slices.Compact(targets)
+
+	if !opts.Force {
+		var staged, modified, both []string
+		for _, path := range targets {
+			if CheckFileForConflicts(path) {
+				continue
+			}
+			index, work := entries[path], worktreeHash(path)
+			stagedChange := index != head[path]
+			localChange := work != "" && work != index
+			switch {
+			case stagedChange && localChange:
+				both = append(both, path)
+			case opts.Cached:
+			case stagedChange:
+				staged = append(staged, path)
+			case localChange:
+				modified = append(modified, path)
+			}
+		}
+		switch {
+		case len(both) > 0:
+			return fmt.Errorf("the following files have staged content different from both the file and the HEAD:\n    %s\n(use -f to force removal)", strings.Join(both, "\n    "))
+		case len(staged) > 0:
+			return fmt.Errorf("the following files have changes staged in the index:\n    %s\n(use --cached to keep the file, or -f to force removal)", strings.Join(staged, "\n    "))
+		case len(modified) > 0:
+			return fmt.Errorf("the following files have local modifications:\n    %s\n(use --cached to keep the file, or -f to force removal)", strings.Join(modified, "\n    "))
+		}
+	}
+
+	for _, path := range targets {
+		delete(entries, path)
+		markConflictResolved(path, nil)
+	}
+	if err := writeIndexEntries(entries); err != nil {
+		return err
+	}
+
+	for _, path := range targets {
+		fmt.Printf("rm '%s'\n", path)
+		if opts.Cached {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %v", path, err)
+		}
+		removeEmptyParents(filepath.Dir(path))
+	}
+	return nil
+}
+
+// removeEmptyParents removes dir and its parents while they are empty,
+// stopping at the top of the repository.
+func removeEmptyParents(dir string) {
+	for dir != "." && dir != "" {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// MovePath moves or renames the tracked file or directory src to dst in
+// both the working tree and the index. When dst is an existing directory,
+// src is moved into it. An existing destination file is only replaced with
+// force.
+func MovePath(src, dst string, force bool) error {
+	from, err := repoPath(src)
+	if err != nil {
+		return err
+	}
+	to, err := repoPath(dst)
+	if err != nil {
+		return err
+	}
+	if from == "." {
+		return fmt.Errorf("cannot move the top of the repository")
+	}
+
+	entries := readIndexEntries()
+	moved := indexPathsUnder(entries, from)
+	if len(moved) == 0 {
+		return fmt.Errorf("not under version control, source=%s, destination=%s", src, dst)
+	}
+	info, err := os.Stat(from)
+	if err != nil {
+		return fmt.Errorf("bad source, source=%s, destination=%s", src, dst)
+	}
+	if dstInfo, err := os.Stat(to); err == nil && dstInfo.IsDir() {
+		to = filepath.ToSlash(filepath.Join(to, filepath.Base(from)))
+	}
+	if to == from {
+		return fmt.Errorf("source and destination are the same, source=%s, destination=%s", src, dst)
+	}
+	if strings.HasPrefix(to, from+"/") {
+		return fmt.Errorf("cannot move a directory into itself, source=%s, destination=%s", src, dst)
+	}
+	for _, path := range moved {
+		if CheckFileForConflicts(path) {
+			return fmt.Errorf("%s has unresolved merge conflicts", path)
+		}
+	}
+
+	if dstInfo, err := os.Stat(to); err == nil {
+		if info.IsDir() || dstInfo.IsDir() || !force {
+			return fmt.Errorf("destination exists, source=%s, destination=%s", src, dst)
+		}
+	}
+	if info.IsDir() {
+		for _, path := range indexPathsUnder(entries, to) {
+			if !force {
+				return fmt.Errorf("destination exists in the index, source=%s, destination=%s", src, dst)
+			}
+			delete(entries, path)
+		}
+	} else {
+		// A forced move replaces the destination's entry.
+		delete(entries, to)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(from, to); err != nil {
+		return fmt.Errorf("failed to move %s to %s: %v", src, dst, err)
+	}
+	removeEmptyParents(filepath.Dir(from))
+
+	renamed := make(map[string]string, len(entries))
+	for path, hash := range entries {
+		if path == from || strings.HasPrefix(path, from+"/") {
+			path = to + strings.TrimPrefix(path, from)
+		}
+		renamed[path] = hash
+	}
+	if err := writeIndexEntries(renamed); err != nil {
+		return err
+	}
+	for _, path := range slices.Sorted(maps.Keys(renamed)) {
+		if path == to || strings.HasPrefix(path, to+"/") {
+			fmt.Printf("Renamed %s -> %s\n", from+strings.TrimPrefix(path, to), path)
+		}
+	}
+	return nil
+}
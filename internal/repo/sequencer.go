@@ -0,0 +1,387 @@
+package repo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/airbornharsh/hit/internal/go_types"
+	"github.com/airbornharsh/hit/internal/storage"
+)
+
+// Operations run by the sequencer
+const (
+	SequencerCherryPick = "cherry-pick"
+	SequencerRevert     = "revert"
+)
+
+// SequencerState is the progress of a cherry-pick or revert, kept in
+// .hit/sequencer.json while it runs so it can be continued or aborted after
+// a conflict.
+type SequencerState struct {
+	Operation    string            `json:"operation"`
+	Branch       string            `json:"branch"`
+	OrigHead     string            `json:"origHead"`
+	OrigLog      []go_types.Commit `json:"origLog"`
+	Todo         []go_types.Commit `json:"todo"`
+	Mainline     int               `json:"mainline,omitempty"`
+	RecordOrigin bool              `json:"recordOrigin,omitempty"`
+}
+
+// CherryPickOptions controls how commits are picked.
+type CherryPickOptions struct {
+	Mainline     int  // parent number (1 or 2) to diff merge commits against
+	RecordOrigin bool // append "(cherry picked from commit ...)" to the message
+}
+
+func sequencerStatePath() string {
+	return filepath.Join(".hit", "sequencer.json")
+}
+
+// LoadSequencerState reads the sequencer state, returning nil when no
+// cherry-pick or revert is in progress.
+func LoadSequencerState() (*SequencerState, error) {
+	data, err := os.ReadFile(sequencerStatePath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state SequencerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse sequencer state: %v", err)
+	}
+	return &state, nil
+}
+
+func (s *SequencerState) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal sequencer state: %v", err)
+	}
+	return os.WriteFile(sequencerStatePath(), data, 0644)
+}
+
+// SequencerOperation returns the operation in progress, or "" if none is.
+func SequencerOperation() string {
+	state, err := LoadSequencerState()
+	if err != nil || state == nil {
+		return ""
+	}
+	return state.Operation
+}
+
+// CherryPick applies the changes each of revs made to its parent onto the
+// current branch, creating one commit per revision with the original author
+// and message.
+func CherryPick(revs []string, opts CherryPickOptions) error {
+	return startSequencer(SequencerCherryPick, revs, opts.Mainline, opts.RecordOrigin)
+}
+
+// ContinueCherryPick commits the resolved conflicts and carries on.
+func ContinueCherryPick() error {
+	return continueSequencer(SequencerCherryPick)
+}
+
+// SkipCherryPick drops the commit that failed to apply and carries on.
+func SkipCherryPick() error {
+	return skipSequencer(SequencerCherryPick)
+}
+
+// AbortCherryPick returns the branch to where it was before the cherry-pick.
+func AbortCherryPick() error {
+	return abortSequencer(SequencerCherryPick)
+}
+
+// RevertOptions controls how commits are reverted.
+type RevertOptions struct {
+	Mainline int // parent number (1 or 2) whose side of a merge is kept
+}
+
+// Revert records a new commit for each of revs that undoes the changes it
+// made to its parent.
+func Revert(revs []string, opts RevertOptions) error {
+	return startSequencer(SequencerRevert, revs, opts.Mainline, false)
+}
+
+// ContinueRevert commits the resolved conflicts and carries on.
+func ContinueRevert() error {
+	return continueSequencer(SequencerRevert)
+}
+
+// SkipRevert drops the revert that failed to apply and carries on.
+func SkipRevert() error {
+	return skipSequencer(SequencerRevert)
+}
+
+// AbortRevert returns the branch to where it was before the revert.
+func AbortRevert() error {
+	return abortSequencer(SequencerRevert)
+}
+
+func startSequencer(operation string, revs []string, mainline int, recordOrigin bool) error {
+	if op := SequencerOperation(); op != "" {
+		return fmt.Errorf("a %s is already in progress; run 'hit %s --continue', '--skip' or '--abort'", op, op)
+	}
+	if IsRebaseInProgress() {
+		return fmt.Errorf("cannot %s: a rebase is in progress", operation)
+	}
+	if cr, _ := LoadConflictResolution(); cr != nil {
+		return fmt.Errorf("cannot %s: you have unfinished merge or conflict state", operation)
+	}
+	if err := ensureCleanWorkTree(); err != nil {
+		return fmt.Errorf("cannot %s: %v", operation, err)
+	}
+	if mainline < 0 || mainline > 2 {
+		return fmt.Errorf("invalid mainline parent %d", mainline)
+	}
+
+	branch, err := storage.GetBranch()
+	if err != nil {
+		return err
+	}
+	head, err := getLocalBranchCommit(branch)
+	if err != nil {
+		return fmt.Errorf("failed to get current branch commit: %v", err)
+	}
+	graph, err := loadCommitGraph()
+	if err != nil {
+		return fmt.Errorf("failed to load commit graph: %v", err)
+	}
+
+	state := &SequencerState{
+		Operation:    operation,
+		Branch:       branch,
+		OrigHead:     head,
+		Mainline:     mainline,
+		RecordOrigin: recordOrigin,
+	}
+	for _, rev := range revs {
+		hash, err := ResolveRevision(rev)
+		if err != nil {
+			return err
+		}
+		c, ok := graph[hash]
+		if !ok {
+			return fmt.Errorf("commit %s not found in any branch log", shortHash(hash))
+		}
+		if _, err := state.stepParent(c); err != nil {
+			return err
+		}
+		state.Todo = append(state.Todo, c)
+	}
+	state.OrigLog, _ = storage.GetHeadCommits(branch)
+
+	return runSequencer(state)
+}
+
+// stepParent picks the parent c is diffed against, honouring the mainline
+// option for merge commits.
+func (s *SequencerState) stepParent(c go_types.Commit) (string, error) {
+	if c.OtherParent == "" {
+		if s.Mainline != 0 {
+			return "", fmt.Errorf("mainline was specified but commit %s is not a merge", shortHash(c.Hash))
+		}
+		return c.Parent, nil
+	}
+	switch s.Mainline {
+	case 1:
+		return c.Parent, nil
+	case 2:
+		return c.OtherParent, nil
+	default:
+		return "", fmt.Errorf("commit %s is a merge but no -m option was given", shortHash(c.Hash))
+	}
+}
+
+// stepChange returns the change to apply for c and the message to commit it
+// with. A revert applies the change from c back to its parent.
+func (s *SequencerState) stepChange(c go_types.Commit) (treeChange, string, error) {
+	parent, err := s.stepParent(c)
+	if err != nil {
+		return treeChange{}, "", err
+	}
+
+	if s.Operation == SequencerRevert {
+		message := "Revert \"" + firstLine(c.Message) + "\"\n\nThis reverts commit " + c.Hash
+		if c.OtherParent != "" {
+			message += ", reversing\nchanges made to " + parent
+		}
+		change := treeChange{
+			Base:        c.Hash,
+			Target:      parent,
+			BaseLabel:   shortHash(c.Hash) + " (" + firstLine(c.Message) + ")",
+			TargetLabel: "parent of " + shortHash(c.Hash),
+		}
+		return change, message + ".", nil
+	}
+
+	message := c.Message
+	if s.RecordOrigin {
+		message += "\n\n(cherry picked from commit " + c.Hash + ")"
+	}
+	change := treeChange{
+		Base:        parent,
+		Target:      c.Hash,
+		BaseLabel:   "parent of " + shortHash(c.Hash),
+		TargetLabel: shortHash(c.Hash) + " (" + firstLine(c.Message) + ")",
+	}
+	return change, message, nil
+}
+
+// stepAuthor is the author of the commit made for c: a cherry-pick keeps
+// the original author, a revert is authored by the current user.
+func (s *SequencerState) stepAuthor(c go_types.Commit) Identity {
+	if s.Operation == SequencerRevert {
+		return Identity{}
+	}
+	return CommitAuthor(c)
+}
+
+// runSequencer applies the remaining commits, stopping at the first conflict.
+func runSequencer(state *SequencerState) error {
+	for len(state.Todo) > 0 {
+		if err := state.save(); err != nil {
+			return err
+		}
+
+		c := state.Todo[0]
+		change, message, err := state.stepChange(c)
+		if err != nil {
+			return err
+		}
+		before, err := getLocalBranchCommit(state.Branch)
+		if err != nil {
+			return err
+		}
+		conflicted, err := applyChange(state.Branch, change, message, state.stepAuthor(c), false, state.Operation+": "+firstLine(message))
+		if err != nil {
+			return err
+		}
+		if conflicted {
+			verb := "apply"
+			if state.Operation == SequencerRevert {
+				verb = "revert"
+			}
+			fmt.Printf("Could not %s %s... %s\n", verb, shortHash(c.Hash), firstLine(c.Message))
+			fmt.Println("Resolve all conflicts manually, mark them as resolved with 'hit add <file>',")
+			fmt.Printf("then run 'hit %s --continue'. To skip this commit run 'hit %s --skip';\n", state.Operation, state.Operation)
+			fmt.Printf("to go back to where you started run 'hit %s --abort'.\n", state.Operation)
+			if err := state.save(); err != nil {
+				return err
+			}
+			return ErrStoppedOnConflicts
+		}
+		if after, _ := getLocalBranchCommit(state.Branch); after != before {
+			fmt.Printf("[%s %s] %s\n", state.Branch, shortHash(after), firstLine(message))
+		}
+		state.Todo = state.Todo[1:]
+	}
+
+	if err := os.Remove(sequencerStatePath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// loadSequencerFor loads the state of operation, failing when a different
+// operation, or none, is in progress.
+func loadSequencerFor(operation string) (*SequencerState, error) {
+	state, err := LoadSequencerState()
+	if err != nil {
+		return nil, err
+	}
+	if state == nil || state.Operation != operation {
+		return nil, fmt.Errorf("no %s in progress", operation)
+	}
+	return state, nil
+}
+
+func continueSequencer(operation string) error {
+	state, err := loadSequencerFor(operation)
+	if err != nil {
+		return err
+	}
+
+	cr, err := LoadConflictResolution()
+	if err != nil {
+		return err
+	}
+	if cr != nil && cr.HasUnresolvedConflicts() {
+		var paths []string
+		for _, conflict := range cr.GetUnresolvedConflicts() {
+			paths = append(paths, conflict.FilePath)
+		}
+		return fmt.Errorf("unresolved conflicts in %v; resolve them and run 'hit add' first", paths)
+	}
+
+	if len(state.Todo) > 0 {
+		c := state.Todo[0]
+		_, message, err := state.stepChange(c)
+		if err != nil {
+			return err
+		}
+		if cr != nil && cr.Message != "" {
+			message = cr.Message
+		}
+		before, err := getLocalBranchCommit(state.Branch)
+		if err != nil {
+			return err
+		}
+		if err := commitIndex(state.Branch, message, state.stepAuthor(c), operation+" (continue): "+firstLine(message)); err != nil {
+			return err
+		}
+		if after, _ := getLocalBranchCommit(state.Branch); after != before {
+			fmt.Printf("[%s %s] %s\n", state.Branch, shortHash(after), firstLine(message))
+		}
+		state.Todo = state.Todo[1:]
+	}
+	if cr != nil {
+		if err := ClearConflictResolution(); err != nil {
+			return err
+		}
+	}
+
+	return runSequencer(state)
+}
+
+func skipSequencer(operation string) error {
+	state, err := loadSequencerFor(operation)
+	if err != nil {
+		return err
+	}
+
+	if err := discardStepChanges(state.Branch); err != nil {
+		return err
+	}
+	if len(state.Todo) > 0 {
+		fmt.Printf("Skipped %s %s\n", shortHash(state.Todo[0].Hash), firstLine(state.Todo[0].Message))
+		state.Todo = state.Todo[1:]
+	}
+
+	return runSequencer(state)
+}
+
+func abortSequencer(operation string) error {
+	state, err := loadSequencerFor(operation)
+	if err != nil {
+		return err
+	}
+
+	if err := discardStepChanges(state.Branch); err != nil {
+		return err
+	}
+	if err := updateBranchRef(state.Branch, state.OrigHead, operation+" (abort): returning to refs/heads/"+state.Branch); err != nil {
+		return err
+	}
+	if err := storage.UpdateHeadCommits(state.Branch, state.OrigLog); err != nil {
+		return fmt.Errorf("failed to restore branch log: %v", err)
+	}
+	if err := storage.UpdateWorkingDirectoryAndIndexFromCommit(state.OrigHead); err != nil {
+		return fmt.Errorf("failed to update working directory and index: %v", err)
+	}
+
+	return os.Remove(sequencerStatePath())
+}
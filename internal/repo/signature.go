@@ -0,0 +1,194 @@
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/airbornharsh/hit/internal/config"
+	"github.com/airbornharsh/hit/internal/go_types"
+	"github.com/airbornharsh/hit/internal/signing"
+	"github.com/airbornharsh/hit/internal/storage"
+)
+
+// ErrNoSignature is returned when verifying a commit or tag that was not
+// signed.
+var ErrNoSignature = errors.New("no signature")
+
+// SignatureStatus is the result of checking a signed commit or tag.
+type SignatureStatus struct {
+	Fingerprint string
+	Principal   string // empty when the key is not an allowed signer
+}
+
+// Good reports whether the signature was made by an allowed signer.
+func (s *SignatureStatus) Good() bool {
+	return s.Principal != ""
+}
+
+// SigningKeyPath is the private key used to sign: $HIT_SIGNING_KEY,
+// user.signingKey, or the key created by 'hit signing-key generate'.
+func SigningKeyPath() (string, error) {
+	if path := os.Getenv("HIT_SIGNING_KEY"); path != "" {
+		return path, nil
+	}
+	if path, ok := config.Get("user.signingKey"); ok {
+		return path, nil
+	}
+	return signing.DefaultKeyPath()
+}
+
+// AllowedSignersPath is the OpenSSH allowed signers file used to verify:
+// $HIT_ALLOWED_SIGNERS, signing.allowedSigners, or .hit/allowed_signers.
+func AllowedSignersPath() string {
+	if path := os.Getenv("HIT_ALLOWED_SIGNERS"); path != "" {
+		return path
+	}
+	if path, ok := config.Get("signing.allowedSigners"); ok {
+		return path
+	}
+	return filepath.Join(".hit", "allowed_signers")
+}
+
+// commitPayload is the data a commit signature covers: the commit object
+// without its signature together with the commit metadata kept in the
+// branch log.
+func commitPayload(tree go_types.Tree, c go_types.Commit) ([]byte, error) {
+	tree.Signature = ""
+	c.Hash = ""
+	return json.Marshal(struct {
+		Tree   go_types.Tree   `json:"tree"`
+		Commit go_types.Commit `json:"commit"`
+	}{tree, c})
+}
+
+func tagPayload(tag go_types.Tag) ([]byte, error) {
+	tag.Signature = ""
+	return json.Marshal(tag)
+}
+
+// loadSigner returns a function signing payloads with the signing key.
+func loadSigner() (func([]byte) string, error) {
+	path, err := SigningKeyPath()
+	if err != nil {
+		return nil, err
+	}
+	key, err := signing.LoadPrivateKey(path)
+	if err != nil {
+		return nil, err
+	}
+	return func(payload []byte) string {
+		return signing.Sign(key, payload)
+	}, nil
+}
+
+// SignCommit signs c and stores the signature in its commit object. The
+// signed object gets a new hash, which is written back to c.Hash.
+func SignCommit(c *go_types.Commit) error {
+	sign, err := loadSigner()
+	if err != nil {
+		return err
+	}
+	tree, err := storage.GetCommitTree(c.Hash)
+	if err != nil {
+		return fmt.Errorf("failed to get commit tree: %v", err)
+	}
+	payload, err := commitPayload(*tree, *c)
+	if err != nil {
+		return err
+	}
+	tree.Signature = sign(payload)
+	hash, err := storeTree(tree)
+	if err != nil {
+		return fmt.Errorf("failed to store signed commit: %v", err)
+	}
+	c.Hash = hash
+	return nil
+}
+
+// signTag signs an annotated tag object in place.
+func signTag(tag *go_types.Tag) error {
+	sign, err := loadSigner()
+	if err != nil {
+		return err
+	}
+	payload, err := tagPayload(*tag)
+	if err != nil {
+		return err
+	}
+	tag.Signature = sign(payload)
+	return nil
+}
+
+// checkSignature verifies signature over payload and looks the key up in
+// the allowed signers file.
+func checkSignature(signature string, payload []byte) (*SignatureStatus, error) {
+	pub, err := signing.Verify(signature, payload)
+	if err != nil {
+		return nil, err
+	}
+	status := &SignatureStatus{Fingerprint: signing.Fingerprint(pub)}
+	signers, err := signing.LoadAllowedSigners(AllowedSignersPath())
+	if err != nil {
+		return status, err
+	}
+	status.Principal, _ = signing.FindPrincipal(signers, pub)
+	return status, nil
+}
+
+// VerifyCommitSignature checks the signature of commit c. It returns an
+// error when the commit is unsigned or the signature does not match.
+func VerifyCommitSignature(c go_types.Commit) (*SignatureStatus, error) {
+	tree, err := storage.GetCommitTree(c.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit tree: %v", err)
+	}
+	if tree.Signature == "" {
+		return nil, fmt.Errorf("commit %s has %w", shortHash(c.Hash), ErrNoSignature)
+	}
+	payload, err := commitPayload(*tree, c)
+	if err != nil {
+		return nil, err
+	}
+	return checkSignature(tree.Signature, payload)
+}
+
+// VerifyCommit checks the signature of the commit rev resolves to.
+func VerifyCommit(rev string) (*go_types.Commit, *SignatureStatus, error) {
+	hash, err := ResolveRevision(rev)
+	if err != nil {
+		return nil, nil, err
+	}
+	graph, err := loadCommitGraph()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load commit graph: %v", err)
+	}
+	c, ok := graph[hash]
+	if !ok {
+		return nil, nil, fmt.Errorf("commit %s not found in any branch log", shortHash(hash))
+	}
+	status, err := VerifyCommitSignature(c)
+	return &c, status, err
+}
+
+// VerifyTag checks the signature of an annotated tag.
+func VerifyTag(name string) (*go_types.Tag, *SignatureStatus, error) {
+	info, err := readTag(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.Annotation == nil {
+		return nil, nil, fmt.Errorf("%s is a lightweight tag and cannot be signed", name)
+	}
+	if info.Annotation.Signature == "" {
+		return info.Annotation, nil, fmt.Errorf("tag %s has %w", name, ErrNoSignature)
+	}
+	payload, err := tagPayload(*info.Annotation)
+	if err != nil {
+		return nil, nil, err
+	}
+	status, err := checkSignature(info.Annotation.Signature, payload)
+	return info.Annotation, status, err
+}
@@ -25,6 +25,23 @@ func getRelativePath(absPath string) (string, error) {
 	return relPath, nil
 }
 
+// repoPath turns a path given on the command line into the slash separated
+// path the index uses.
+func repoPath(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	relPath, err := getRelativePath(absPath)
+	if err != nil {
+		return "", err
+	}
+	if relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("'%s' is outside the repository", path)
+	}
+	return filepath.ToSlash(relPath), nil
+}
+
 // AddFile reads, hashes, compresses, and stores the file in .hit/objects
 func AddFile(filePath string) (string, error) {
 	absPath, err := filepath.Abs(filePath)
@@ -35,6 +52,8 @@ func AddFile(filePath string) (string, error) {
 	if _, err := os.Stat(absPath); os.IsNotExist(err) {
 		relPath, _ := getRelativePath(absPath)
 		removeFromIndex(relPath, nil)
+		// Staging the deletion resolves a modify/delete conflict
+		markConflictResolved(relPath, nil)
 		return "", fmt.Errorf("file does not exist: %s", filePath)
 	}
 
@@ -60,13 +79,7 @@ func AddFile(filePath string) (string, error) {
 		return "", fmt.Errorf("file contains unresolved conflict markers: %s (resolve conflicts before adding)", filePath)
 	}
 
-	conflictResolution, err := LoadConflictResolution()
-	if err == nil && conflictResolution != nil {
-		if CheckFileForConflicts(relPath) {
-			conflictResolution.MarkResolved(relPath)
-			conflictResolution.SaveConflictResolution()
-		}
-	}
+	markConflictResolved(relPath, content)
 
 	hash := storage.Hash(content)
 
@@ -97,6 +110,26 @@ func AddFile(filePath string) (string, error) {
 	return hash, nil
 }
 
+// markConflictResolved marks a conflicted file as resolved and, when rerere
+// is enabled, records the staged content as its resolution. A nil content
+// means the file was deleted.
+func markConflictResolved(relPath string, content []byte) {
+	conflictResolution, err := LoadConflictResolution()
+	if err == nil && conflictResolution != nil {
+		if CheckFileForConflicts(relPath) {
+			for _, conflict := range conflictResolution.Conflicts {
+				if conflict.FilePath == relPath && content != nil {
+					if err := rerereRecord(conflict, content); err != nil {
+						fmt.Printf("warning: failed to record resolution for %s: %v\n", relPath, err)
+					}
+				}
+			}
+			conflictResolution.MarkResolved(relPath)
+			conflictResolution.SaveConflictResolution()
+		}
+	}
+}
+
 func AddAllFile(currentDir string) error {
 	var pwd = "/"
 	if currentDir == "." {
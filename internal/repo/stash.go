@@ -0,0 +1,444 @@
+package repo
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/airbornharsh/hit/internal/go_types"
+	"github.com/airbornharsh/hit/internal/storage"
+)
+
+// StashEntry is one set of stashed changes. Index and WorkTree are trees
+// holding the staged and working tree state of tracked files on top of Head;
+// Untracked holds untracked files when they were included.
+type StashEntry struct {
+	Message   string    `json:"message"`
+	Branch    string    `json:"branch"`
+	Head      string    `json:"head"`
+	Index     string    `json:"index"`
+	WorkTree  string    `json:"workTree"`
+	Untracked string    `json:"untracked,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// StashChange is a file changed by a stash entry.
+type StashChange struct {
+	Path   string
+	Status string // "A", "M", "D" or "?" for untracked
+	Old    string
+	New    string
+}
+
+var stashRefPattern = regexp.MustCompile(`^(?:stash@\{(\d+)\}|(\d+))$`)
+
+func stashPath() string {
+	return filepath.Join(".hit", "stash.json")
+}
+
+// ListStashes returns the stash entries, newest first.
+func ListStashes() ([]StashEntry, error) {
+	data, err := os.ReadFile(stashPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []StashEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse stash list: %v", err)
+	}
+	return entries, nil
+}
+
+func saveStashes(entries []StashEntry) error {
+	if len(entries) == 0 {
+		if err := os.Remove(stashPath()); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal stash list: %v", err)
+	}
+	return os.WriteFile(stashPath(), data, 0644)
+}
+
+// ParseStashRef turns "stash@{n}" or "n" into an index into the stash list.
+// An empty ref is the latest stash.
+func ParseStashRef(ref string) (int, error) {
+	if ref == "" {
+		return 0, nil
+	}
+	m := stashRefPattern.FindStringSubmatch(ref)
+	if m == nil {
+		return 0, fmt.Errorf("%s is not a valid stash reference", ref)
+	}
+	digits := m[1] + m[2]
+	return strconv.Atoi(digits)
+}
+
+func loadStash(n int) (*StashEntry, []StashEntry, error) {
+	entries, err := ListStashes()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(entries) == 0 {
+		return nil, nil, fmt.Errorf("no stash entries found")
+	}
+	if n < 0 || n >= len(entries) {
+		return nil, nil, fmt.Errorf("stash@{%d} does not exist", n)
+	}
+	return &entries[n], entries, nil
+}
+
+// StashPush saves the staged and unstaged changes to tracked files, and
+// untracked files when includeUntracked is set, then resets the working tree
+// and index to HEAD.
+func StashPush(message string, includeUntracked bool) error {
+	if cr, _ := LoadConflictResolution(); cr != nil {
+		return fmt.Errorf("cannot stash: you have unfinished merge or conflict state")
+	}
+
+	branch, err := storage.GetBranch()
+	if err != nil {
+		return err
+	}
+	head, err := getLocalBranchCommit(branch)
+	if err != nil {
+		return fmt.Errorf("cannot stash before the first commit: %v", err)
+	}
+	headTree, err := storage.GetCommitTree(head)
+	if err != nil {
+		return fmt.Errorf("failed to get head tree: %v", err)
+	}
+
+	index := readIndexEntries()
+	working, err := getAllWorkingFiles()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory files: %v", err)
+	}
+
+	workEntries := make(map[string]string)
+	untracked := make(map[string]string)
+	for path := range working {
+		_, inIndex := index[path]
+		_, inHead := headTree.Entries[path]
+		if !inIndex && !inHead && !includeUntracked {
+			continue
+		}
+		hash, err := storeWorkingFile(path)
+		if err != nil {
+			return err
+		}
+		if inIndex || inHead {
+			workEntries[path] = hash
+		} else {
+			untracked[path] = hash
+		}
+	}
+
+	if maps.Equal(index, headTree.Entries) && maps.Equal(workEntries, index) && len(untracked) == 0 {
+		fmt.Println("No local changes to save")
+		return nil
+	}
+
+	entry := StashEntry{
+		Branch:    branch,
+		Head:      head,
+		Timestamp: go_types.TimeNow(),
+	}
+	if entry.Index, err = storeTree(&go_types.Tree{Entries: index, Parent: head}); err != nil {
+		return fmt.Errorf("failed to store index tree: %v", err)
+	}
+	if entry.WorkTree, err = storeTree(&go_types.Tree{Entries: workEntries, Parent: head}); err != nil {
+		return fmt.Errorf("failed to store working tree: %v", err)
+	}
+	if len(untracked) > 0 {
+		if entry.Untracked, err = storeTree(&go_types.Tree{Entries: untracked}); err != nil {
+			return fmt.Errorf("failed to store untracked files: %v", err)
+		}
+	}
+	if message != "" {
+		entry.Message = "On " + branch + ": " + message
+	} else {
+		subject := ""
+		if c, err := branchHeadCommit(branch); err == nil {
+			subject = firstLine(c.Message)
+		}
+		entry.Message = "WIP on " + branch + ": " + shortHash(head) + " " + subject
+	}
+
+	entries, err := ListStashes()
+	if err != nil {
+		return err
+	}
+	if err := saveStashes(append([]StashEntry{entry}, entries...)); err != nil {
+		return err
+	}
+
+	// Put every file the stash touched back to its HEAD version.
+	for path := range workEntries {
+		if err := restoreHeadFile(path, headTree); err != nil {
+			return err
+		}
+	}
+	for path := range index {
+		if err := restoreHeadFile(path, headTree); err != nil {
+			return err
+		}
+	}
+	for path := range untracked {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := writeIndexEntries(headTree.Entries); err != nil {
+		return err
+	}
+
+	fmt.Printf("Saved working directory and index state %s\n", entry.Message)
+	return nil
+}
+
+// StashApply reapplies a stash onto the current working tree with a
+// three-way merge against the commit it was made on. With restoreIndex the
+// staged state is brought back too. It reports whether the result has
+// conflicts, in which case they are left for the user to resolve.
+func StashApply(n int, restoreIndex bool) (bool, error) {
+	entry, _, err := loadStash(n)
+	if err != nil {
+		return false, err
+	}
+	if cr, _ := LoadConflictResolution(); cr != nil {
+		return false, fmt.Errorf("cannot apply stash: you have unfinished merge or conflict state")
+	}
+	if err := ensureNoTrackedChanges(); err != nil {
+		return false, fmt.Errorf("cannot apply stash: %v", err)
+	}
+
+	headTree, err := storage.GetHeadTree()
+	if err != nil {
+		return false, fmt.Errorf("failed to get head tree: %v", err)
+	}
+	baseTree, err := storage.GetCommitTree(entry.Head)
+	if err != nil {
+		return false, fmt.Errorf("failed to get stash base: %v", err)
+	}
+	workTree, err := storage.GetCommitTree(entry.WorkTree)
+	if err != nil {
+		return false, fmt.Errorf("failed to get stashed working tree: %v", err)
+	}
+
+	untracked := map[string]string{}
+	if entry.Untracked != "" {
+		tree, err := storage.GetCommitTree(entry.Untracked)
+		if err != nil {
+			return false, fmt.Errorf("failed to get stashed untracked files: %v", err)
+		}
+		untracked = tree.Entries
+		for path := range untracked {
+			if _, err := os.Stat(path); err == nil {
+				return false, fmt.Errorf("%s already exists, no checkout", path)
+			}
+		}
+	}
+
+	var indexEntries map[string]string
+	if restoreIndex {
+		stashedIndex, err := storage.GetCommitTree(entry.Index)
+		if err != nil {
+			return false, fmt.Errorf("failed to get stashed index: %v", err)
+		}
+		scratch := CreateConflictResolution()
+		result, err := mergeTrees(headTree, stashedIndex, baseTree, ConflictMarkers{}, "", scratch)
+		if err != nil {
+			return false, err
+		}
+		if result.HasConflicts {
+			return false, fmt.Errorf("conflicts in index; try without --index")
+		}
+		indexEntries = result.Tree.Entries
+	}
+
+	markers := ConflictMarkers{
+		Current: "Updated upstream",
+		Base:    "Stash base",
+		Target:  "Stashed changes",
+	}
+	cr := CreateConflictResolution()
+	result, err := mergeTrees(headTree, workTree, baseTree, markers, "", cr)
+	if err != nil {
+		return false, err
+	}
+
+	for path, hash := range untracked {
+		if err := storage.RestoreFileFromObject(path, hash); err != nil {
+			return false, err
+		}
+	}
+
+	if result.HasConflicts {
+		if err := updateWorkingDirectoryWithConflictsAndNonConflicts(cr, result.NonConflict, result.Deleted); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	for path, hash := range result.Tree.Entries {
+		if headTree.Entries[path] != hash {
+			if err := storage.RestoreFileFromObject(path, hash); err != nil {
+				return false, err
+			}
+		}
+	}
+	for path := range headTree.Entries {
+		if _, ok := result.Tree.Entries[path]; !ok {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return false, err
+			}
+		}
+	}
+
+	if indexEntries == nil {
+		// Without --index the changes are left unstaged, except that new
+		// files are added so they stay tracked.
+		indexEntries = maps.Clone(headTree.Entries)
+		for path, hash := range result.Tree.Entries {
+			if _, ok := headTree.Entries[path]; !ok {
+				indexEntries[path] = hash
+			}
+		}
+		for path := range indexEntries {
+			if _, ok := result.Tree.Entries[path]; !ok {
+				delete(indexEntries, path)
+			}
+		}
+	}
+	if err := writeIndexEntries(indexEntries); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// StashDrop removes a stash entry and returns it.
+func StashDrop(n int) (*StashEntry, error) {
+	entry, entries, err := loadStash(n)
+	if err != nil {
+		return nil, err
+	}
+	dropped := *entry
+	if err := saveStashes(slices.Delete(entries, n, n+1)); err != nil {
+		return nil, err
+	}
+	return &dropped, nil
+}
+
+// StashClear removes every stash entry.
+func StashClear() error {
+	return saveStashes(nil)
+}
+
+// StashChanges lists the files a stash entry changed relative to the commit
+// it was made on.
+func StashChanges(n int) (*StashEntry, []StashChange, error) {
+	entry, _, err := loadStash(n)
+	if err != nil {
+		return nil, nil, err
+	}
+	baseTree, err := storage.GetCommitTree(entry.Head)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get stash base: %v", err)
+	}
+	workTree, err := storage.GetCommitTree(entry.WorkTree)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get stashed working tree: %v", err)
+	}
+
+	var changes []StashChange
+	for path, hash := range workTree.Entries {
+		if old, ok := baseTree.Entries[path]; !ok {
+			changes = append(changes, StashChange{Path: path, Status: "A", New: hash})
+		} else if old != hash {
+			changes = append(changes, StashChange{Path: path, Status: "M", Old: old, New: hash})
+		}
+	}
+	for path, old := range baseTree.Entries {
+		if _, ok := workTree.Entries[path]; !ok {
+			changes = append(changes, StashChange{Path: path, Status: "D", Old: old})
+		}
+	}
+	if entry.Untracked != "" {
+		if tree, err := storage.GetCommitTree(entry.Untracked); err == nil {
+			for path, hash := range tree.Entries {
+				changes = append(changes, StashChange{Path: path, Status: "?", New: hash})
+			}
+		}
+	}
+	slices.SortFunc(changes, func(a, b StashChange) int {
+		if a.Path < b.Path {
+			return -1
+		} else if a.Path > b.Path {
+			return 1
+		}
+		return 0
+	})
+	return entry, changes, nil
+}
+
+// storeWorkingFile writes the content of a working tree file to the object
+// store and returns its hash.
+func storeWorkingFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file %s: %v", path, err)
+	}
+	hash := storage.Hash(content)
+	if err := storage.WriteObject(hash, content); err != nil {
+		return "", fmt.Errorf("failed to store file %s: %v", path, err)
+	}
+	return hash, nil
+}
+
+// restoreHeadFile puts path back to its version in headTree, removing it
+// when HEAD does not have it.
+func restoreHeadFile(path string, headTree *go_types.Tree) error {
+	if hash, ok := headTree.Entries[path]; ok {
+		return storage.RestoreFileFromObject(path, hash)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// ensureNoTrackedChanges fails when the index differs from HEAD or a
+// tracked file differs from the index. Untracked files are allowed.
+func ensureNoTrackedChanges() error {
+	headTree, err := storage.GetHeadTree()
+	if err != nil {
+		return nil
+	}
+	index := readIndexEntries()
+	if !maps.Equal(index, headTree.Entries) {
+		return fmt.Errorf("your index contains uncommitted changes")
+	}
+	working, err := getAllWorkingFiles()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory files: %v", err)
+	}
+	for path, hash := range index {
+		if working[path] != hash {
+			return fmt.Errorf("you have local changes to %s", path)
+		}
+	}
+	return nil
+}
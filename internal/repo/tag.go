@@ -0,0 +1,263 @@
+package repo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/airbornharsh/hit/internal/apis"
+	"github.com/airbornharsh/hit/internal/go_types"
+	"github.com/airbornharsh/hit/internal/storage"
+)
+
+// TagInfo describes a tag. Annotation is nil for lightweight tags.
+type TagInfo struct {
+	Name       string
+	Ref        string // commit or tag object the tag points at
+	Commit     string
+	Annotation *go_types.Tag
+}
+
+func tagsDir() string {
+	return filepath.Join(".hit", "refs", "tags")
+}
+
+func tagRefPath(name string) string {
+	return filepath.Join(tagsDir(), filepath.FromSlash(name))
+}
+
+func validateTagName(name string) error {
+	switch {
+	case name == "", strings.HasPrefix(name, "-"), strings.HasPrefix(name, "/"), strings.HasSuffix(name, "/"):
+		return fmt.Errorf("'%s' is not a valid tag name", name)
+	case strings.Contains(name, ".."), strings.Contains(name, "@{"), strings.Contains(name, "//"):
+		return fmt.Errorf("'%s' is not a valid tag name", name)
+	case strings.ContainsAny(name, " \t\n~^:?*[\\"):
+		return fmt.Errorf("'%s' is not a valid tag name", name)
+	}
+	return nil
+}
+
+// readTagObject loads hash as an annotated tag object, reporting false when
+// it is something else, such as a commit.
+func readTagObject(hash string) (*go_types.Tag, bool) {
+	data, err := storage.LoadObject(hash)
+	if err != nil {
+		return nil, false
+	}
+	var tag go_types.Tag
+	if err := json.Unmarshal([]byte(data), &tag); err != nil || tag.Tag == "" || tag.Object == "" {
+		return nil, false
+	}
+	return &tag, true
+}
+
+// readTag looks up a tag by name.
+func readTag(name string) (*TagInfo, error) {
+	data, err := os.ReadFile(tagRefPath(name))
+	if err != nil {
+		return nil, fmt.Errorf("tag '%s' not found", name)
+	}
+	info := &TagInfo{Name: name, Ref: strings.TrimSpace(string(data))}
+	info.Commit = info.Ref
+	if tag, ok := readTagObject(info.Ref); ok {
+		info.Annotation = tag
+		info.Commit = tag.Object
+	}
+	return info, nil
+}
+
+// tagCommit returns the commit a tag points at.
+func tagCommit(name string) (string, error) {
+	if validateTagName(name) != nil {
+		return "", fmt.Errorf("tag '%s' not found", name)
+	}
+	info, err := readTag(name)
+	if err != nil {
+		return "", err
+	}
+	return info.Commit, nil
+}
+
+// CreateTag points a new tag at rev. With annotate a tag object recording
+// the tagger, date and message is created, and signed when sign is set; an
+// empty message is asked for in the editor. An existing tag is only replaced
+// when force is set.
+func CreateTag(name, rev, message string, annotate, sign, force bool) error {
+	if err := validateTagName(name); err != nil {
+		return err
+	}
+	if _, err := os.Stat(tagRefPath(name)); err == nil && !force {
+		return fmt.Errorf("tag '%s' already exists", name)
+	}
+	if rev == "" {
+		rev = "HEAD"
+	}
+	commit, err := ResolveRevision(rev)
+	if err != nil {
+		return err
+	}
+
+	ref := commit
+	if annotate || sign {
+		if message == "" {
+			message, err = editText("TAG_EDITMSG", "",
+				"# Write a message for tag:\n#   "+name+"\n# Lines starting with '#' will be ignored.\n",
+				"tag")
+			if err != nil {
+				return err
+			}
+		}
+		tagger, err := CommitterIdentity()
+		if err != nil {
+			return err
+		}
+		tag := go_types.Tag{
+			Object:    commit,
+			Tag:       name,
+			Tagger:    tagger.String(),
+			Message:   message,
+			Timestamp: tagger.When,
+		}
+		if sign {
+			if err := signTag(&tag); err != nil {
+				return fmt.Errorf("failed to sign tag: %v", err)
+			}
+		}
+		data, err := json.Marshal(tag)
+		if err != nil {
+			return fmt.Errorf("failed to marshal tag: %v", err)
+		}
+		ref = storage.Hash(data)
+		if err := storage.WriteObject(ref, data); err != nil {
+			return fmt.Errorf("failed to store tag: %v", err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(tagRefPath(name)), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(tagRefPath(name), []byte(ref), 0644)
+}
+
+// DeleteTag removes a tag and returns what it pointed at.
+func DeleteTag(name string) (string, error) {
+	info, err := readTag(name)
+	if err != nil {
+		return "", err
+	}
+	if err := os.Remove(tagRefPath(name)); err != nil {
+		return "", err
+	}
+	return info.Ref, nil
+}
+
+// ListTags returns the tags whose names match the glob pattern, sorted by
+// name. An empty pattern matches every tag.
+func ListTags(pattern string) ([]TagInfo, error) {
+	var tags []TagInfo
+	err := filepath.WalkDir(tagsDir(), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(tagsDir(), p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if pattern != "" {
+			if ok, _ := path.Match(pattern, name); !ok {
+				return nil
+			}
+		}
+		info, err := readTag(name)
+		if err != nil {
+			return err
+		}
+		tags = append(tags, *info)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(tags, func(a, b TagInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return tags, nil
+}
+
+// PushTags uploads every local tag to the remote. The pre-push hook runs
+// first unless noVerify is set.
+func PushTags(remoteName string, noVerify bool) error {
+	remote, err := GetRemoteURL(remoteName)
+	if err != nil {
+		return err
+	}
+	tags, err := ListTags("")
+	if err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		fmt.Println("No tags to push")
+		return nil
+	}
+
+	if !noVerify {
+		var lines []string
+		for _, tag := range tags {
+			ref := "refs/tags/" + tag.Name
+			lines = append(lines, prePushLine(ref, tag.Ref, ref, ""))
+		}
+		if err := runPrePushHook(remoteName, remote, lines); err != nil {
+			return err
+		}
+	}
+
+	if err := apis.UploadAllFiles(remote); err != nil {
+		return err
+	}
+
+	refs := make([]go_types.TagRef, 0, len(tags))
+	for _, tag := range tags {
+		refs = append(refs, go_types.TagRef{Name: tag.Name, Hash: tag.Ref})
+	}
+	if err := apis.PushTags(remote, refs); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		fmt.Printf(" * [new tag] %s -> %s\n", tag.Name, tag.Name)
+	}
+	return nil
+}
+
+// saveFetchedTags creates the remote's tags locally. Existing local tags are
+// left alone.
+func saveFetchedTags(tags []go_types.TagRef) error {
+	for _, tag := range tags {
+		if validateTagName(tag.Name) != nil || tag.Hash == "" {
+			continue
+		}
+		if _, err := os.Stat(tagRefPath(tag.Name)); err == nil {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(tagRefPath(tag.Name)), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(tagRefPath(tag.Name), []byte(tag.Hash), 0644); err != nil {
+			return fmt.Errorf("failed to create tag %s: %v", tag.Name, err)
+		}
+		fmt.Printf(" * [new tag] %s -> %s\n", tag.Name, tag.Name)
+	}
+	return nil
+}
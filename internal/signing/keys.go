@@ -0,0 +1,185 @@
+package signing
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AllowedSigner is one line of an allowed signers file: the principals a
+// key may sign as.
+type AllowedSigner struct {
+	Principals []string
+	Key        ed25519.PublicKey
+}
+
+// DefaultKeyPath is where GenerateKey puts a key when no path is given.
+func DefaultKeyPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "hit", "signing_key"), nil
+}
+
+// GenerateKey creates a new ed25519 key and writes it to path as a PKCS#8
+// PEM file readable only by the owner.
+func GenerateKey(path string) (ed25519.PublicKey, error) {
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("%s already exists", path)
+	}
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return nil, err
+	}
+	return pub, nil
+}
+
+// LoadPrivateKey reads an ed25519 private key from a PKCS#8 PEM file or an
+// unencrypted OpenSSH private key file.
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM encoded key", path)
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse signing key: %v", err)
+		}
+		edKey, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%s is not an ed25519 key", path)
+		}
+		return edKey, nil
+	case "OPENSSH PRIVATE KEY":
+		return parseOpenSSHPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported key type %q in %s", block.Type, path)
+	}
+}
+
+// parseOpenSSHPrivateKey decodes the "openssh-key-v1" format written by
+// ssh-keygen.
+func parseOpenSSHPrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	const magic = "openssh-key-v1\x00"
+	if !bytes.HasPrefix(data, []byte(magic)) {
+		return nil, fmt.Errorf("malformed OpenSSH private key")
+	}
+	rest := data[len(magic):]
+
+	var fields [3][]byte
+	for i := range fields {
+		var ok bool
+		if fields[i], rest, ok = readString(rest); !ok {
+			return nil, fmt.Errorf("malformed OpenSSH private key")
+		}
+	}
+	if string(fields[0]) != "none" || string(fields[1]) != "none" {
+		return nil, fmt.Errorf("encrypted OpenSSH keys are not supported; remove the passphrase or use a PEM key")
+	}
+	if len(rest) < 4 || binary.BigEndian.Uint32(rest) != 1 {
+		return nil, fmt.Errorf("OpenSSH key files with several keys are not supported")
+	}
+	// Skip the public key, which the private section repeats.
+	_, rest, ok := readString(rest[4:])
+	if !ok {
+		return nil, fmt.Errorf("malformed OpenSSH private key")
+	}
+
+	private, _, ok := readString(rest)
+	if !ok || len(private) < 8 {
+		return nil, fmt.Errorf("malformed OpenSSH private key")
+	}
+	if !bytes.Equal(private[:4], private[4:8]) {
+		return nil, fmt.Errorf("malformed OpenSSH private key")
+	}
+	private = private[8:]
+
+	keyType, private, ok := readString(private)
+	if !ok || string(keyType) != keyTypeEd25519 {
+		return nil, fmt.Errorf("unsupported OpenSSH key type %q; only ed25519 keys can sign", keyType)
+	}
+	if _, private, ok = readString(private); !ok {
+		return nil, fmt.Errorf("malformed OpenSSH private key")
+	}
+	key, _, ok := readString(private)
+	if !ok || len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("malformed OpenSSH private key")
+	}
+	return ed25519.PrivateKey(key), nil
+}
+
+// LoadAllowedSigners reads an OpenSSH allowed signers file. Each line holds
+// comma separated principals, optional options and an ssh-ed25519 key. Keys
+// of other types are skipped.
+func LoadAllowedSigners(path string) ([]AllowedSigner, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read allowed signers: %v", err)
+	}
+
+	var signers []AllowedSigner
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		for i := 1; i+1 < len(fields); i++ {
+			if fields[i] != keyTypeEd25519 {
+				continue
+			}
+			blob, err := base64.StdEncoding.DecodeString(fields[i+1])
+			if err != nil {
+				break
+			}
+			pub, err := parsePublicKeyBlob(blob)
+			if err != nil {
+				break
+			}
+			signers = append(signers, AllowedSigner{
+				Principals: strings.Split(fields[0], ","),
+				Key:        pub,
+			})
+			break
+		}
+	}
+	return signers, scanner.Err()
+}
+
+// FindPrincipal returns the principals allowed to sign with pub.
+func FindPrincipal(signers []AllowedSigner, pub ed25519.PublicKey) (string, bool) {
+	for _, signer := range signers {
+		if signer.Key.Equal(pub) {
+			return strings.Join(signer.Principals, ","), true
+		}
+	}
+	return "", false
+}
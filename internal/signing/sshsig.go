@@ -0,0 +1,161 @@
+// Package signing signs and verifies data with ed25519 keys using the
+// OpenSSH signature format (SSHSIG), so signatures can also be checked with
+// `ssh-keygen -Y verify`.
+package signing
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Namespace is the SSHSIG namespace hit signatures are made in.
+const Namespace = "hit"
+
+const (
+	sigMagic       = "SSHSIG"
+	sigVersion     = 1
+	sigHash        = "sha512"
+	keyTypeEd25519 = "ssh-ed25519"
+	armorBegin     = "-----BEGIN SSH SIGNATURE-----"
+	armorEnd       = "-----END SSH SIGNATURE-----"
+)
+
+func appendString(b []byte, s []byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+func readString(b []byte) ([]byte, []byte, bool) {
+	if len(b) < 4 {
+		return nil, nil, false
+	}
+	n := binary.BigEndian.Uint32(b)
+	if uint64(len(b)-4) < uint64(n) {
+		return nil, nil, false
+	}
+	return b[4 : 4+n], b[4+n:], true
+}
+
+// publicKeyBlob is the SSH wire encoding of an ed25519 public key.
+func publicKeyBlob(pub ed25519.PublicKey) []byte {
+	b := appendString(nil, []byte(keyTypeEd25519))
+	return appendString(b, pub)
+}
+
+func parsePublicKeyBlob(blob []byte) (ed25519.PublicKey, error) {
+	keyType, rest, ok := readString(blob)
+	if !ok || string(keyType) != keyTypeEd25519 {
+		return nil, fmt.Errorf("unsupported key type %q", keyType)
+	}
+	key, _, ok := readString(rest)
+	if !ok || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("malformed ed25519 public key")
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// PublicKeyString formats pub as an authorized_keys style line.
+func PublicKeyString(pub ed25519.PublicKey) string {
+	return keyTypeEd25519 + " " + base64.StdEncoding.EncodeToString(publicKeyBlob(pub))
+}
+
+// Fingerprint returns the SHA256 fingerprint of pub as shown by ssh-keygen.
+func Fingerprint(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(publicKeyBlob(pub))
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// signedData is what an SSHSIG signature actually signs.
+func signedData(namespace string, message []byte) []byte {
+	digest := sha512.Sum512(message)
+	b := []byte(sigMagic)
+	b = appendString(b, []byte(namespace))
+	b = appendString(b, nil)
+	b = appendString(b, []byte(sigHash))
+	return appendString(b, digest[:])
+}
+
+// Sign signs message with key and returns an armored SSH signature.
+func Sign(key ed25519.PrivateKey, message []byte) string {
+	sig := ed25519.Sign(key, signedData(Namespace, message))
+
+	blob := []byte(sigMagic)
+	blob = binary.BigEndian.AppendUint32(blob, sigVersion)
+	blob = appendString(blob, publicKeyBlob(key.Public().(ed25519.PublicKey)))
+	blob = appendString(blob, []byte(Namespace))
+	blob = appendString(blob, nil)
+	blob = appendString(blob, []byte(sigHash))
+	blob = appendString(blob, appendString(appendString(nil, []byte(keyTypeEd25519)), sig))
+
+	encoded := base64.StdEncoding.EncodeToString(blob)
+	var armored strings.Builder
+	armored.WriteString(armorBegin + "\n")
+	for len(encoded) > 70 {
+		armored.WriteString(encoded[:70] + "\n")
+		encoded = encoded[70:]
+	}
+	armored.WriteString(encoded + "\n" + armorEnd + "\n")
+	return armored.String()
+}
+
+// Verify checks an armored SSH signature over message and returns the key
+// that made it.
+func Verify(armored string, message []byte) (ed25519.PublicKey, error) {
+	body := strings.TrimSpace(armored)
+	if !strings.HasPrefix(body, armorBegin) || !strings.HasSuffix(body, armorEnd) {
+		return nil, fmt.Errorf("not an SSH signature")
+	}
+	body = strings.Join(strings.Fields(body[len(armorBegin):len(body)-len(armorEnd)]), "")
+	blob, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return nil, fmt.Errorf("malformed signature: %v", err)
+	}
+
+	if !bytes.HasPrefix(blob, []byte(sigMagic)) || len(blob) < len(sigMagic)+4 {
+		return nil, fmt.Errorf("malformed signature")
+	}
+	rest := blob[len(sigMagic):]
+	if binary.BigEndian.Uint32(rest) != sigVersion {
+		return nil, fmt.Errorf("unsupported signature version")
+	}
+	rest = rest[4:]
+
+	var fields [5][]byte
+	for i := range fields {
+		var ok bool
+		if fields[i], rest, ok = readString(rest); !ok {
+			return nil, fmt.Errorf("malformed signature")
+		}
+	}
+	pubBlob, namespace, hashAlg, sigBlob := fields[0], fields[1], fields[3], fields[4]
+	if string(namespace) != Namespace {
+		return nil, fmt.Errorf("signature namespace is %q, expected %q", namespace, Namespace)
+	}
+	if string(hashAlg) != sigHash {
+		return nil, fmt.Errorf("unsupported signature hash %q", hashAlg)
+	}
+
+	pub, err := parsePublicKeyBlob(pubBlob)
+	if err != nil {
+		return nil, err
+	}
+	sigType, sigRest, ok := readString(sigBlob)
+	if !ok || string(sigType) != keyTypeEd25519 {
+		return nil, fmt.Errorf("unsupported signature type %q", sigType)
+	}
+	sig, _, ok := readString(sigRest)
+	if !ok {
+		return nil, fmt.Errorf("malformed signature")
+	}
+
+	if !ed25519.Verify(pub, signedData(Namespace, message), sig) {
+		return nil, fmt.Errorf("bad signature from %s", Fingerprint(pub))
+	}
+	return pub, nil
+}
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/airbornharsh/hit/internal/config"
 	"github.com/sergi/go-diff/diffmatchpatch"
 )
 
@@ -36,14 +37,16 @@ func formatColoredLineDiff(diffs []diffmatchpatch.Diff) string {
 		return ""
 	}
 
-	// ANSI colors
-	const (
-		reset  = "\x1b[0m"
-		red    = "\x1b[31m"
-		green  = "\x1b[32m"
-		yellow = "\x1b[33m"
-		blue   = "\x1b[34m"
+	// ANSI colors, unless turned off with color.ui
+	var (
+		reset = "\x1b[0m"
+		red   = "\x1b[31m"
+		green = "\x1b[32m"
+		blue  = "\x1b[34m"
 	)
+	if !config.GetBool("color.ui", true) {
+		reset, red, green, blue = "", "", "", ""
+	}
 
 	var b strings.Builder
 
@@ -51,7 +54,7 @@ func formatColoredLineDiff(diffs []diffmatchpatch.Diff) string {
 	lineNum2 := 1
 
 	wroteAny := false
-	contextLines := 2
+	contextLines := max(0, config.GetInt("diff.context", 2))
 
 	type lineInfo struct {
 		content    string
@@ -112,26 +112,6 @@ func GetCurrentCommit(branch string) (string, error) {
 	return strings.TrimSpace(string(data)), nil
 }
 
-func GetConfig() (*go_types.RemoteConfig, error) {
-	filePath := filepath.Join(".hit", "config")
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("config file does not exist")
-	}
-
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil, err
-	}
-
-	var config go_types.RemoteConfig
-	err = json.Unmarshal(data, &config)
-	if err != nil {
-		return nil, err
-	}
-
-	return &config, nil
-}
-
 func GetRemoteCommits(remoteName, branchName string) ([]go_types.Commit, error) {
 	logRemotePath := filepath.Join(".hit", "logs", "refs", "remotes", remoteName, branchName)
 	data, err := os.ReadFile(logRemotePath)
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/airbornharsh/hit/internal/config"
+)
+
+// Editor returns the command used to edit files, taken from HIT_EDITOR,
+// core.editor, VISUAL or EDITOR, in that order.
+func Editor() string {
+	if editor := os.Getenv("HIT_EDITOR"); editor != "" {
+		return editor
+	}
+	if editor, ok := config.Get("core.editor"); ok {
+		return editor
+	}
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if editor := os.Getenv(name); editor != "" {
+			return editor
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return "notepad"
+	}
+	return "vi"
+}
+
+// LaunchEditor opens path in the user's editor and waits for it to exit.
+func LaunchEditor(path string) error {
+	editor := Editor()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", editor+` "`+path+`"`)
+	} else {
+		cmd = exec.Command("sh", "-c", editor+` "$1"`, editor, path)
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor '%s' failed: %v", editor, err)
+	}
+	return nil
+}